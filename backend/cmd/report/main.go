@@ -0,0 +1,104 @@
+// Command report prints the low-result search keyword report, the same
+// data as GET /api/admin/reports/low-results.
+//
+//	go run ./cmd/report -from 2024-03-01 -to 2024-03-31 -max-results 0
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jju-compass/jju-compass-map/internal/config"
+	"github.com/jju-compass/jju-compass-map/internal/database"
+	"github.com/jju-compass/jju-compass-map/internal/repository"
+)
+
+// kst is the time zone of the -from and -to dates
+var kst = time.FixedZone("KST", 9*60*60)
+
+func main() {
+	cfg := config.Load()
+
+	dbPath := flag.String("db", cfg.Database.Path, "SQLite database path")
+	fromFlag := flag.String("from", "", "first day, YYYY-MM-DD in KST (default 30 days before -to)")
+	toFlag := flag.String("to", "", "last day, YYYY-MM-DD in KST (default today)")
+	maxResults := flag.Int("max-results", 2, "count searches returning at most this many results")
+	minSearches := flag.Int("min-searches", 1, "hide keywords searched fewer times")
+	samples := flag.Int("samples", 3, "sample timestamps per keyword")
+	limit := flag.Int("limit", 50, "maximum number of keywords")
+	asJSON := flag.Bool("json", false, "print JSON instead of a table")
+	flag.Parse()
+
+	// Without -to the report is open-ended, up to now
+	var to time.Time
+	until := time.Now()
+	if *toFlag != "" {
+		day, err := time.ParseInLocation("2006-01-02", *toFlag, kst)
+		if err != nil {
+			log.Fatalf("Invalid -to: %v", err)
+		}
+		to = day.AddDate(0, 0, 1)
+		until = to
+	}
+	from := until.AddDate(0, 0, -30)
+	if *fromFlag != "" {
+		day, err := time.ParseInLocation("2006-01-02", *fromFlag, kst)
+		if err != nil {
+			log.Fatalf("Invalid -from: %v", err)
+		}
+		from = day
+	}
+	if !from.Before(until) {
+		log.Fatal("-from must be before -to")
+	}
+
+	if err := database.Connect(*dbPath); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer database.Close()
+	// A report must not migrate the database it reads
+	if err := database.CheckSchema(); err != nil {
+		log.Fatalf("Database not ready: %v", err)
+	}
+
+	report, err := repository.NewReportRepository(database.DB).LowResultKeywords(repository.LowResultOptions{
+		From:        from,
+		To:          to,
+		MaxResults:  *maxResults,
+		MinSearches: *minSearches,
+		Samples:     *samples,
+		Limit:       *limit,
+	})
+	if err != nil {
+		log.Fatalf("Failed to build report: %v", err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	fmt.Printf("검색 결과 %d건 이하 키워드 (%s ~ %s)\n\n", *maxResults,
+		from.In(kst).Format("2006-01-02 15:04"), until.In(kst).Format("2006-01-02 15:04"))
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KEYWORD\tSEARCHES\tZERO\tUSERS\tMAX RESULTS\tRECENT SEARCHES")
+	for _, k := range report {
+		times := make([]string, len(k.Samples))
+		for i, t := range k.Samples {
+			times[i] = t.In(kst).Format("01-02 15:04")
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%s\n", k.Keyword, k.Searches, k.ZeroResults,
+			k.Users, k.MaxResultCount, strings.Join(times, ", "))
+	}
+	w.Flush()
+}
@@ -15,7 +15,12 @@ import (
 	"github.com/jju-compass/jju-compass-map/internal/config"
 	"github.com/jju-compass/jju-compass-map/internal/database"
 	"github.com/jju-compass/jju-compass-map/internal/handler"
+	"github.com/jju-compass/jju-compass-map/internal/jobs"
 	"github.com/jju-compass/jju-compass-map/internal/middleware"
+	"github.com/jju-compass/jju-compass-map/internal/moderation"
+	"github.com/jju-compass/jju-compass-map/internal/placesource"
+	"github.com/jju-compass/jju-compass-map/internal/repository"
+	"github.com/jju-compass/jju-compass-map/internal/suggest"
 )
 
 func main() {
@@ -33,25 +38,81 @@ func main() {
 		log.Fatalf("Failed to initialize database schema: %v", err)
 	}
 
+	// Background jobs stop when the server shuts down
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	historyRepo := repository.NewHistoryRepository(database.DB)
+	go jobs.EveryFromStart(jobCtx, "purge-deleted", cfg.Deletion.PurgeInterval, jobs.PurgeDeleted(
+		repository.NewFavoriteRepository(database.DB),
+		historyRepo,
+		cfg.Deletion.GracePeriod,
+	))
+	go jobs.EveryFromStart(jobCtx, "prune-trends", time.Hour, jobs.PruneTrends(historyRepo))
+	go jobs.EveryFromStart(jobCtx, "rollup-search-events", 24*time.Hour, jobs.RollupSearchEvents(
+		historyRepo, cfg.Stats.EventRetention, cfg.Moderation.UserCap))
+	personalData := repository.NewPersonalDataRepository(database.DB)
+	if _, err := personalData.RequeueInterrupted(); err != nil {
+		log.Printf("Failed to requeue deletion jobs: %v", err)
+	}
+	go jobs.Every(jobCtx, "process-deletions", time.Minute, jobs.ProcessDeletions(personalData))
+	go jobs.EveryFromStart(jobCtx, "purge-sessions", time.Hour, jobs.PurgeSessions(repository.NewUserRepository(database.DB), 7*24*time.Hour))
+	if cfg.Audit.Retention < repository.AuditMinRetention {
+		log.Printf("AUDIT_RETENTION_DAYS is below the minimum, keeping audit entries for %v", repository.AuditMinRetention)
+		cfg.Audit.Retention = repository.AuditMinRetention
+	}
+	go jobs.EveryFromStart(jobCtx, "purge-audit", 24*time.Hour, jobs.PurgeAudit(repository.NewAuditRepository(database.DB), cfg.Audit.Retention))
+
+	// Rules for keywords shown publicly (popular, trending, autocomplete)
+	rules := loadModerationRules(cfg)
+
+	// Build the autocomplete index, then refresh it periodically
+	suggestIndex := suggest.NewIndex()
+	rebuildSuggestions := jobs.RebuildSuggestions(suggestIndex, historyRepo, repository.NewCacheRepository(database.DB),
+		repository.PopularOptions{UserCap: cfg.Moderation.UserCap, Exclude: rules.Blocked})
+	if err := rebuildSuggestions(jobCtx); err != nil {
+		log.Printf("Failed to build autocomplete index: %v", err)
+	}
+	go jobs.Every(jobCtx, "rebuild-suggestions", 30*time.Minute, rebuildSuggestions)
+
+	// Re-verify favorited places against the place source
+	if source := newPlaceSource(cfg); source != nil {
+		go jobs.Every(jobCtx, "verify-favorites", cfg.Verify.Interval, jobs.VerifyFavorites(
+			repository.NewVerificationRepository(database.DB),
+			source,
+			cfg.Verify.BatchSize,
+			cfg.Verify.RecheckAfter,
+		))
+	}
+
 	// Set Gin mode
 	gin.SetMode(cfg.Server.Mode)
 
 	// Create router
 	router := gin.New()
+	if err := middleware.TrustProxies(router, cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 
 	// Apply middlewares
 	router.Use(middleware.CORS(&cfg.CORS))
-	rateLimiter := middleware.NewRateLimiter(100, time.Minute) // 100 requests per minute
-	router.Use(rateLimiter.RateLimit())
+	rateLimiter := newRateLimiter(cfg)
 
 	// Create daily API limiter for Kakao Directions
 	apiLimiter := middleware.NewDailyAPILimiter(cfg.Kakao.DailyAPILimit)
 
 	// Create handlers and register routes
-	handlers := handler.NewHandlers(database.DB, cfg, apiLimiter)
-	handlers.RegisterRoutes(router)
+	handlers := handler.NewHandlers(database.DB, cfg, apiLimiter, rateLimiter, suggestIndex, rules)
+	identity, err := middleware.NewIdentity(&cfg.Identity)
+	if err != nil {
+		log.Fatalf("Invalid identity configuration: %v", err)
+	}
+	if cfg.Identity.LegacyCookie != "" {
+		identity.AcceptLegacy(repository.NewUserRepository(database.DB).ClaimLegacyID)
+	}
+	handlers.RegisterRoutes(router, identity.Middleware())
 
 	// Serve static files from frontend/dist
 	staticPath := cfg.Static.Path
@@ -92,6 +153,7 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopJobs()
 
 	// Close database connection
 	if err := database.Close(); err != nil {
@@ -108,3 +170,66 @@ func main() {
 
 	log.Println("Server exited")
 }
+
+// newPlaceSource creates the configured place source, or nil if verification is disabled
+func newPlaceSource(cfg *config.Config) placesource.Source {
+	switch cfg.Verify.Source {
+	case "":
+		return nil
+	case "kakao":
+		if cfg.Kakao.APIKey == "" {
+			log.Println("Place verification disabled: Kakao API key not configured")
+			return nil
+		}
+		return placesource.NewKakaoSource(cfg.Kakao.LocalAPIURL, cfg.Kakao.APIKey,
+			middleware.NewDailyAPILimiter(cfg.Verify.DailyLimit))
+	case "stub":
+		source, err := placesource.NewStubSource(cfg.Verify.StubPath)
+		if err != nil {
+			log.Fatalf("Failed to load place verification stub: %v", err)
+		}
+		return source
+	}
+	log.Fatalf("Unknown place verification source: %s", cfg.Verify.Source)
+	return nil
+}
+
+// loadModerationRules compiles the configured keyword blocklist
+func loadModerationRules(cfg *config.Config) *moderation.Rules {
+	entries := cfg.Moderation.Blocklist
+	if cfg.Moderation.BlocklistFile != "" {
+		fileEntries, err := moderation.ReadFile(cfg.Moderation.BlocklistFile)
+		if err != nil {
+			log.Fatalf("Failed to read keyword blocklist: %v", err)
+		}
+		entries = append(entries, fileEntries...)
+	}
+	rules, err := moderation.Compile(entries)
+	if err != nil {
+		log.Fatalf("Invalid keyword blocklist: %v", err)
+	}
+	return rules
+}
+
+// newRateLimiter builds the rate limit policies used by the API routes
+func newRateLimiter(cfg *config.Config) *middleware.RateLimiter {
+	specs := []struct {
+		name, spec string
+		key        middleware.RateLimitKey
+	}{
+		{"default", cfg.RateLimit.Default, middleware.KeyByIdentity},
+		{"polling", cfg.RateLimit.Polling, middleware.KeyByIdentity},
+		{"directions", cfg.RateLimit.Directions, middleware.KeyByIdentity},
+		{"auth", cfg.RateLimit.Auth, middleware.KeyByIP},
+		{"ip", cfg.RateLimit.IP, middleware.KeyByIP},
+	}
+	var policies []middleware.RateLimitPolicy
+	for _, s := range specs {
+		p, err := middleware.ParseRateLimitPolicy(s.name, s.spec, s.key)
+		if err != nil {
+			log.Fatalf("Invalid rate limit configuration: %v", err)
+		}
+		policies = append(policies, p)
+	}
+	return middleware.NewRateLimiter(nil, policies...)
+}
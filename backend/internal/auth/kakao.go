@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jju-compass/jju-compass-map/internal/config"
+)
+
+// ProviderKakao is the provider name stored with Kakao accounts
+const ProviderKakao = "kakao"
+
+// ErrNotConfigured is returned when Kakao login has no client ID
+var ErrNotConfigured = errors.New("kakao login is not configured")
+
+// Profile is the identity returned by the provider
+type Profile struct {
+	Provider        string
+	ProviderUserID  string
+	Nickname        string
+	ProfileImageURL string
+}
+
+// KakaoClient runs the Kakao OAuth2 authorization code flow.
+// The endpoints come from configuration so that a local fake provider
+// can stand in for Kakao.
+type KakaoClient struct {
+	cfg        *config.AuthConfig
+	httpClient *http.Client
+}
+
+// NewKakaoClient creates a Kakao OAuth client
+func NewKakaoClient(cfg *config.AuthConfig) *KakaoClient {
+	return &KakaoClient{cfg: cfg, httpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Enabled reports whether Kakao login is configured
+func (k *KakaoClient) Enabled() bool {
+	return k.cfg.KakaoClientID != ""
+}
+
+// AuthCodeURL returns the Kakao login page URL for state
+func (k *KakaoClient) AuthCodeURL(state string) string {
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", k.cfg.KakaoClientID)
+	params.Set("redirect_uri", k.cfg.KakaoRedirectURL)
+	params.Set("state", state)
+	return k.cfg.KakaoAuthURL + "?" + params.Encode()
+}
+
+// Authenticate exchanges an authorization code for the user's profile
+func (k *KakaoClient) Authenticate(ctx context.Context, code string) (*Profile, error) {
+	if !k.Enabled() {
+		return nil, ErrNotConfigured
+	}
+	token, err := k.exchange(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	return k.profile(ctx, token)
+}
+
+// exchange trades the authorization code for an access token
+func (k *KakaoClient) exchange(ctx context.Context, code string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("client_id", k.cfg.KakaoClientID)
+	form.Set("redirect_uri", k.cfg.KakaoRedirectURL)
+	form.Set("code", code)
+	if k.cfg.KakaoClientSecret != "" {
+		form.Set("client_secret", k.cfg.KakaoClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", k.cfg.KakaoTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded;charset=utf-8")
+
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("kakao token: status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.AccessToken == "" {
+		return "", errors.New("kakao token: no access token")
+	}
+	return body.AccessToken, nil
+}
+
+// profile fetches the Kakao user behind an access token
+func (k *KakaoClient) profile(ctx context.Context, token string) (*Profile, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", k.cfg.KakaoUserInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("kakao user info: status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		ID           int64 `json:"id"`
+		KakaoAccount struct {
+			Profile struct {
+				Nickname        string `json:"nickname"`
+				ProfileImageURL string `json:"profile_image_url"`
+			} `json:"profile"`
+		} `json:"kakao_account"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	if body.ID == 0 {
+		return nil, errors.New("kakao user info: no user id")
+	}
+
+	return &Profile{
+		Provider:        ProviderKakao,
+		ProviderUserID:  strconv.FormatInt(body.ID, 10),
+		Nickname:        body.KakaoAccount.Profile.Nickname,
+		ProfileImageURL: body.KakaoAccount.Profile.ProfileImageURL,
+	}, nil
+}
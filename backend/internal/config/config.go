@@ -1,17 +1,29 @@
 package config
 
 import (
+	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Kakao    KakaoConfig
-	CORS     CORSConfig
-	Static   StaticConfig
+	Server     ServerConfig
+	Database   DatabaseConfig
+	Kakao      KakaoConfig
+	CORS       CORSConfig
+	Static     StaticConfig
+	Deletion   DeletionConfig
+	Verify     VerifyConfig
+	Moderation ModerationConfig
+	Identity   IdentityConfig
+	Auth       AuthConfig
+	Admin      AdminConfig
+	Audit      AuditConfig
+	RateLimit  RateLimitConfig
+	Stats      StatsConfig
 }
 
 // ServerConfig holds server-related configuration
@@ -20,6 +32,10 @@ type ServerConfig struct {
 	Mode         string // "debug", "release", "test"
 	ReadTimeout  int    // seconds
 	WriteTimeout int    // seconds
+
+	// TrustedProxies are the IPs and CIDRs of reverse proxies whose
+	// X-Forwarded-For and X-Real-IP headers name the client
+	TrustedProxies []string
 }
 
 // DatabaseConfig holds database-related configuration
@@ -31,6 +47,7 @@ type DatabaseConfig struct {
 type KakaoConfig struct {
 	APIKey        string
 	DailyAPILimit int
+	LocalAPIURL   string // base URL of the Kakao Local API
 }
 
 // CORSConfig holds CORS-related configuration
@@ -43,14 +60,87 @@ type StaticConfig struct {
 	Path string
 }
 
+// DeletionConfig holds soft-delete configuration
+type DeletionConfig struct {
+	GracePeriod   time.Duration // how long deleted rows can be restored before purge
+	PurgeInterval time.Duration // how often expired rows are purged
+}
+
+// VerifyConfig holds favorite place re-verification configuration
+type VerifyConfig struct {
+	Source       string // "kakao", "stub" or empty to disable
+	StubPath     string // JSON file used by the stub source
+	Interval     time.Duration
+	BatchSize    int           // places checked per run
+	DailyLimit   int           // max lookups per day
+	RecheckAfter time.Duration // minimum time between checks of a place
+}
+
+// ModerationConfig holds public keyword moderation configuration
+type ModerationConfig struct {
+	Blocklist     []string // terms, or /regexp/ patterns
+	BlocklistFile string   // optional file with one blocklist entry per line
+	UserCap       int      // max searches per user counted toward a keyword's popularity
+}
+
+// IdentityConfig holds the signed identity cookie configuration
+type IdentityConfig struct {
+	Keys         []string // "kid:secret[:YYYY-MM-DD]", first is the signing key
+	CookieName   string
+	Secure       bool
+	MaxAge       time.Duration
+	LegacyCookie string // unsigned cookie of the old identity scheme, adopted once ("" = off)
+}
+
+// AuthConfig holds Kakao login and session configuration
+type AuthConfig struct {
+	KakaoClientID     string // REST API key
+	KakaoClientSecret string
+	KakaoRedirectURL  string
+	KakaoAuthURL      string
+	KakaoTokenURL     string
+	KakaoUserInfoURL  string
+	SessionCookieName string
+	SessionTTL        time.Duration
+}
+
+// AdminConfig holds operator access configuration
+type AdminConfig struct {
+	Tokens []string // static bearer tokens, "name:token"
+}
+
+// AuditConfig holds audit log configuration
+type AuditConfig struct {
+	Retention time.Duration // entries older than this are purged (90 days minimum)
+}
+
+// StatsConfig holds search statistics configuration
+type StatsConfig struct {
+	// EventRetention is how long individual search events are kept.
+	// Older ones only count toward popular keywords, and drop out of
+	// low-result reports.
+	EventRetention time.Duration
+}
+
+// RateLimitConfig holds the request rate policies, each "limit/period/burst"
+type RateLimitConfig struct {
+	Default    string // most API routes
+	Polling    string // cheap routes clients call often, like autocomplete
+	Directions string // routes spending the Kakao Directions quota
+	Auth       string // login, counted per IP
+	IP         string // every API request per IP, before authentication
+}
+
 // Load reads configuration from environment variables with defaults
 func Load() *Config {
+	mode := getEnv("GIN_MODE", "debug")
 	return &Config{
 		Server: ServerConfig{
-			Port:         getEnv("SERVER_PORT", "8080"),
-			Mode:         getEnv("GIN_MODE", "debug"),
-			ReadTimeout:  getEnvAsInt("SERVER_READ_TIMEOUT", 10),
-			WriteTimeout: getEnvAsInt("SERVER_WRITE_TIMEOUT", 10),
+			Port:           getEnv("SERVER_PORT", "8080"),
+			Mode:           mode,
+			ReadTimeout:    getEnvAsInt("SERVER_READ_TIMEOUT", 10),
+			WriteTimeout:   getEnvAsInt("SERVER_WRITE_TIMEOUT", 10),
+			TrustedProxies: getEnvAsListOr("TRUSTED_PROXIES", []string{"127.0.0.1", "::1"}),
 		},
 		Database: DatabaseConfig{
 			Path: getEnv("DB_PATH", "../database/jju_compass.db"),
@@ -58,6 +148,7 @@ func Load() *Config {
 		Kakao: KakaoConfig{
 			APIKey:        getEnv("KAKAO_API_KEY", ""),
 			DailyAPILimit: getEnvAsInt("KAKAO_DAILY_LIMIT", 5000),
+			LocalAPIURL:   getEnv("KAKAO_LOCAL_API_URL", "https://dapi.kakao.com"),
 		},
 		CORS: CORSConfig{
 			AllowedOrigins: []string{
@@ -69,6 +160,58 @@ func Load() *Config {
 		Static: StaticConfig{
 			Path: getEnv("STATIC_PATH", "../frontend/dist"),
 		},
+		Deletion: DeletionConfig{
+			GracePeriod:   time.Duration(getEnvAsInt("DELETE_GRACE_MINUTES", 24*60)) * time.Minute,
+			PurgeInterval: time.Duration(getEnvAsPositiveInt("DELETE_PURGE_INTERVAL_MINUTES", 60)) * time.Minute,
+		},
+		Verify: VerifyConfig{
+			Source:       getEnv("PLACE_VERIFY_SOURCE", ""),
+			StubPath:     getEnv("PLACE_VERIFY_STUB_PATH", ""),
+			Interval:     time.Duration(getEnvAsPositiveInt("PLACE_VERIFY_INTERVAL_MINUTES", 10)) * time.Minute,
+			BatchSize:    getEnvAsPositiveInt("PLACE_VERIFY_BATCH_SIZE", 20),
+			DailyLimit:   getEnvAsPositiveInt("PLACE_VERIFY_DAILY_LIMIT", 1000),
+			RecheckAfter: time.Duration(getEnvAsInt("PLACE_VERIFY_RECHECK_HOURS", 7*24)) * time.Hour,
+		},
+		Moderation: ModerationConfig{
+			Blocklist:     getEnvAsList("KEYWORD_BLOCKLIST"),
+			BlocklistFile: getEnv("KEYWORD_BLOCKLIST_FILE", ""),
+			UserCap:       getEnvAsInt("POPULAR_USER_CAP", 3),
+		},
+		Identity: IdentityConfig{
+			Keys:       getEnvAsList("IDENTITY_KEYS"),
+			CookieName: getEnv("IDENTITY_COOKIE_NAME", "jjk_uid"),
+			// The identity cookie is the only credential of anonymous
+			// users, so it is HTTPS-only unless debugging locally
+			Secure:       getEnvAsBool("IDENTITY_COOKIE_SECURE", mode != "debug"),
+			MaxAge:       time.Duration(getEnvAsInt("IDENTITY_COOKIE_DAYS", 400)) * 24 * time.Hour,
+			LegacyCookie: getEnv("IDENTITY_LEGACY_COOKIE", ""),
+		},
+		Auth: AuthConfig{
+			KakaoClientID:     getEnv("KAKAO_REST_API_KEY", ""),
+			KakaoClientSecret: getEnv("KAKAO_CLIENT_SECRET", ""),
+			KakaoRedirectURL:  getEnv("KAKAO_REDIRECT_URL", "http://localhost:8080/api/auth/kakao/callback"),
+			KakaoAuthURL:      getEnv("KAKAO_AUTH_URL", "https://kauth.kakao.com/oauth/authorize"),
+			KakaoTokenURL:     getEnv("KAKAO_TOKEN_URL", "https://kauth.kakao.com/oauth/token"),
+			KakaoUserInfoURL:  getEnv("KAKAO_USER_INFO_URL", "https://kapi.kakao.com/v2/user/me"),
+			SessionCookieName: getEnv("SESSION_COOKIE_NAME", "jjk_session"),
+			SessionTTL:        time.Duration(getEnvAsInt("SESSION_DAYS", 30)) * 24 * time.Hour,
+		},
+		Admin: AdminConfig{
+			Tokens: getEnvAsList("ADMIN_TOKENS"),
+		},
+		Audit: AuditConfig{
+			Retention: time.Duration(getEnvAsInt("AUDIT_RETENTION_DAYS", 365)) * 24 * time.Hour,
+		},
+		RateLimit: RateLimitConfig{
+			Default:    getEnv("RATE_LIMIT_DEFAULT", "100/1m/30"),
+			Polling:    getEnv("RATE_LIMIT_POLLING", "300/1m/60"),
+			Directions: getEnv("RATE_LIMIT_DIRECTIONS", "10/1m/3"),
+			Auth:       getEnv("RATE_LIMIT_AUTH", "20/1m/5"),
+			IP:         getEnv("RATE_LIMIT_IP", "600/1m/200"),
+		},
+		Stats: StatsConfig{
+			EventRetention: time.Duration(getEnvAsPositiveInt("SEARCH_EVENT_RETENTION_DAYS", 90)) * 24 * time.Hour,
+		},
 	}
 }
 
@@ -89,3 +232,44 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvAsPositiveInt is getEnvAsInt for values that must be above zero,
+// such as intervals. Other values are rejected in favor of the default.
+func getEnvAsPositiveInt(key string, defaultValue int) int {
+	value := getEnvAsInt(key, defaultValue)
+	if value <= 0 {
+		log.Printf("%s must be greater than 0, using %d", key, defaultValue)
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvAsBool returns environment variable as bool or default value
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultValue
+}
+
+// getEnvAsList returns a comma-separated environment variable as a list
+func getEnvAsList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvAsListOr returns a comma-separated environment variable as a
+// list, or defaultValue when it is unset. Set it empty for an empty list.
+func getEnvAsListOr(key string, defaultValue []string) []string {
+	if _, exists := os.LookupEnv(key); !exists {
+		return defaultValue
+	}
+	return getEnvAsList(key)
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"time"
 
 	_ "modernc.org/sqlite"
 )
@@ -11,6 +12,21 @@ import (
 // DB holds the database connection
 var DB *sql.DB
 
+// TimeLayout matches SQLite's CURRENT_TIMESTAMP format so that bound
+// times compare correctly against columns it filled in
+const TimeLayout = "2006-01-02 15:04:05"
+
+// FormatTime formats a time for comparison with CURRENT_TIMESTAMP columns
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(TimeLayout)
+}
+
+// ParseTime parses a time produced by FormatTime or CURRENT_TIMESTAMP.
+// Needed for aggregates such as MAX(), which lose the column's DATETIME type.
+func ParseTime(s string) (time.Time, error) {
+	return time.ParseInLocation(TimeLayout, s, time.UTC)
+}
+
 // Connect establishes a connection to the SQLite database
 func Connect(dbPath string) error {
 	var err error
@@ -88,6 +104,10 @@ func InitSchema() error {
 		return fmt.Errorf("failed to initialize schema: %w", err)
 	}
 
+	if err := migrate(); err != nil {
+		return fmt.Errorf("failed to migrate schema: %w", err)
+	}
+
 	log.Println("Database schema initialized")
 	return nil
 }
@@ -0,0 +1,788 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jju-compass/jju-compass-map/internal/textnorm"
+)
+
+// migration alters the base schema created by InitSchema.
+// Migrations are applied in order and tracked with PRAGMA user_version,
+// so each one runs exactly once per database file.
+type migration struct {
+	name string
+	up   func(tx *sql.Tx) error
+}
+
+// migrations lists all schema changes after the initial schema.
+// Append only: never reorder or edit an entry that has shipped.
+var migrations = []migration{
+	{name: "favorites_updated_at_version", up: migrateFavoritesVersioning},
+	{name: "soft_delete", up: migrateSoftDelete},
+	{name: "place_verifications", up: migratePlaceVerifications},
+	{name: "visits", up: migrateVisits},
+	{name: "shared_lists", up: migrateSharedLists},
+	{name: "dedup_search_history", up: migrateDedupSearchHistory},
+	{name: "keyword_trend_buckets", up: migrateKeywordTrendBuckets},
+	{name: "renormalize_keywords", up: migrateRenormalizeKeywords},
+	{name: "places", up: migratePlaces},
+	{name: "place_catalog", up: migratePlaceCatalog},
+	{name: "keyword_moderation", up: migrateKeywordModeration},
+	{name: "users_sessions", up: migrateUsersSessions},
+	{name: "deletion_jobs", up: migrateDeletionJobs},
+	{name: "user_admin_flag", up: migrateUserAdminFlag},
+	{name: "api_keys", up: migrateAPIKeys},
+	{name: "audit_log", up: migrateAuditLog},
+	{name: "search_keyword_totals", up: migrateSearchKeywordTotals},
+	{name: "legacy_identity_claims", up: migrateLegacyIdentityClaims},
+	{name: "audit_subjects", up: migrateAuditSubjects},
+}
+
+// CheckSchema fails unless every migration has been applied, for tools
+// that read the database but must not change it
+func CheckSchema() error {
+	var version int
+	if err := DB.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version < len(migrations) {
+		return fmt.Errorf("database schema is at version %d, expected %d: start the server once to migrate it",
+			version, len(migrations))
+	}
+	return nil
+}
+
+// migrate applies all pending migrations
+func migrate() error {
+	var version int
+	if err := DB.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+
+	for i := version; i < len(migrations); i++ {
+		m := migrations[i]
+		tx, err := DB.Begin()
+		if err != nil {
+			return err
+		}
+		if err := m.up(tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d (%s): %w", i+1, m.name, err)
+		}
+		// PRAGMA does not accept bound parameters
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		log.Printf("Applied migration %d: %s", i+1, m.name)
+	}
+	return nil
+}
+
+// legacyKeyword is the keyword normalization that dedup_search_history
+// shipped with, frozen here so the migration keeps doing what it did.
+// renormalize_keywords moves its results to textnorm.Key.
+func legacyKeyword(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// execAll runs statements in order within a migration
+func execAll(tx *sql.Tx, stmts ...string) error {
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 즐겨찾기 수정 시각 및 낙관적 동시성 제어용 버전
+func migrateFavoritesVersioning(tx *sql.Tx) error {
+	// ALTER TABLE cannot add a column with a non-constant default,
+	// so updated_at is backfilled from created_at.
+	return execAll(tx,
+		`ALTER TABLE favorites ADD COLUMN updated_at DATETIME`,
+		`ALTER TABLE favorites ADD COLUMN version INTEGER NOT NULL DEFAULT 1`,
+		`UPDATE favorites SET updated_at = created_at WHERE updated_at IS NULL`,
+	)
+}
+
+// 즐겨찾기/검색 기록 소프트 삭제
+func migrateSoftDelete(tx *sql.Tx) error {
+	return execAll(tx,
+		`ALTER TABLE favorites ADD COLUMN deleted_at DATETIME`,
+		`ALTER TABLE search_history ADD COLUMN deleted_at DATETIME`,
+		`CREATE INDEX IF NOT EXISTS idx_favorites_deleted ON favorites(deleted_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_history_deleted ON search_history(deleted_at)`,
+	)
+}
+
+// 즐겨찾기 장소 재검증 결과
+func migratePlaceVerifications(tx *sql.Tx) error {
+	return execAll(tx,
+		`CREATE TABLE IF NOT EXISTS place_verifications (
+			place_id TEXT PRIMARY KEY,
+			status TEXT NOT NULL,
+			place_name TEXT,
+			address TEXT,
+			road_address TEXT,
+			phone TEXT,
+			category TEXT,
+			lat REAL,
+			lng REAL,
+			checked_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_place_verifications_checked ON place_verifications(checked_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_favorites_place ON favorites(place_id)`,
+	)
+}
+
+// 방문 기록 테이블
+func migrateVisits(tx *sql.Tx) error {
+	return execAll(tx,
+		`CREATE TABLE IF NOT EXISTS visits (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id TEXT NOT NULL,
+			place_id TEXT NOT NULL,
+			place_name TEXT NOT NULL,
+			category TEXT,
+			lat REAL,
+			lng REAL,
+			visited_at DATETIME NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_visits_user_time ON visits(user_id, visited_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_visits_user_place ON visits(user_id, place_id)`,
+	)
+}
+
+// 공유 즐겨찾기 목록
+func migrateSharedLists(tx *sql.Tx) error {
+	return execAll(tx,
+		`CREATE TABLE IF NOT EXISTS shared_lists (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			owner_id TEXT NOT NULL,
+			slug TEXT NOT NULL UNIQUE,
+			title TEXT NOT NULL,
+			description TEXT,
+			all_favorites INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			revoked_at DATETIME
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_shared_lists_owner ON shared_lists(owner_id)`,
+		`CREATE TABLE IF NOT EXISTS shared_list_items (
+			list_id INTEGER NOT NULL REFERENCES shared_lists(id) ON DELETE CASCADE,
+			place_id TEXT NOT NULL,
+			PRIMARY KEY (list_id, place_id)
+		)`,
+	)
+}
+
+// 검색 기록을 (사용자, 정규화 키워드)당 한 행으로 합치고,
+// 인기 검색어 집계용 이벤트 스트림을 분리
+func migrateDedupSearchHistory(tx *sql.Tx) error {
+	err := execAll(tx,
+		`CREATE TABLE IF NOT EXISTS search_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id TEXT NOT NULL,
+			keyword TEXT NOT NULL,
+			normalized_keyword TEXT NOT NULL,
+			result_count INTEGER DEFAULT 0,
+			searched_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_search_events_keyword ON search_events(normalized_keyword)`,
+		`CREATE INDEX IF NOT EXISTS idx_search_events_time ON search_events(searched_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_search_events_user ON search_events(user_id)`,
+		`CREATE TABLE search_history_dedup (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id TEXT NOT NULL,
+			keyword TEXT NOT NULL,
+			normalized_keyword TEXT NOT NULL,
+			count INTEGER NOT NULL DEFAULT 1,
+			result_count INTEGER DEFAULT 0,
+			first_searched_at DATETIME NOT NULL,
+			last_searched_at DATETIME NOT NULL,
+			deleted_at DATETIME,
+			UNIQUE(user_id, normalized_keyword)
+		)`,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`
+		SELECT user_id, keyword, result_count, searched_at, deleted_at
+		FROM search_history
+		ORDER BY searched_at, id
+	`)
+	if err != nil {
+		return err
+	}
+
+	type entry struct {
+		userID, keyword, normalized string
+		resultCount                 int
+		searchedAt                  time.Time
+		deletedAt                   sql.NullTime
+	}
+	var entries []entry
+	for rows.Next() {
+		var e entry
+		if err := rows.Scan(&e.userID, &e.keyword, &e.resultCount, &e.searchedAt, &e.deletedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		e.normalized = legacyKeyword(e.keyword)
+		entries = append(entries, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Every old row becomes an event; rows are then folded per keyword,
+	// with a live row reviving a keyword whose earlier rows were deleted
+	for _, e := range entries {
+		if e.normalized == "" {
+			continue
+		}
+		_, err := tx.Exec(`
+			INSERT INTO search_events (user_id, keyword, normalized_keyword, result_count, searched_at)
+			VALUES (?, ?, ?, ?, ?)
+		`, e.userID, e.keyword, e.normalized, e.resultCount, FormatTime(e.searchedAt))
+		if err != nil {
+			return err
+		}
+
+		var deletedAt interface{}
+		if e.deletedAt.Valid {
+			deletedAt = FormatTime(e.deletedAt.Time)
+		}
+		searchedAt := FormatTime(e.searchedAt)
+		_, err = tx.Exec(`
+			INSERT INTO search_history_dedup (user_id, keyword, normalized_keyword, result_count, first_searched_at, last_searched_at, deleted_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(user_id, normalized_keyword) DO UPDATE SET
+				keyword = excluded.keyword,
+				count = CASE
+					WHEN excluded.deleted_at IS NULL AND search_history_dedup.deleted_at IS NOT NULL THEN 1
+					WHEN excluded.deleted_at IS NOT NULL AND search_history_dedup.deleted_at IS NULL THEN count
+					ELSE count + 1 END,
+				result_count = excluded.result_count,
+				first_searched_at = CASE
+					WHEN excluded.deleted_at IS NULL AND search_history_dedup.deleted_at IS NOT NULL THEN excluded.first_searched_at
+					ELSE first_searched_at END,
+				last_searched_at = excluded.last_searched_at,
+				deleted_at = CASE
+					WHEN excluded.deleted_at IS NOT NULL AND search_history_dedup.deleted_at IS NULL THEN NULL
+					ELSE excluded.deleted_at END
+		`, e.userID, e.keyword, e.normalized, e.resultCount, searchedAt, searchedAt, deletedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return execAll(tx,
+		`DROP TABLE search_history`,
+		`ALTER TABLE search_history_dedup RENAME TO search_history`,
+		`CREATE INDEX IF NOT EXISTS idx_history_user ON search_history(user_id, last_searched_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_history_deleted ON search_history(deleted_at)`,
+	)
+}
+
+// 급상승 검색어 집계용 시간 버킷 (10분 단위, 최근 14일 백필)
+func migrateKeywordTrendBuckets(tx *sql.Tx) error {
+	return execAll(tx,
+		`CREATE TABLE IF NOT EXISTS keyword_trend_buckets (
+			normalized_keyword TEXT NOT NULL,
+			bucket_start INTEGER NOT NULL,
+			keyword TEXT NOT NULL,
+			count INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (normalized_keyword, bucket_start)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_trend_buckets_start ON keyword_trend_buckets(bucket_start)`,
+		`INSERT INTO keyword_trend_buckets (normalized_keyword, bucket_start, keyword, count)
+		SELECT normalized_keyword, (CAST(strftime('%s', searched_at) AS INTEGER) / 600) * 600, MAX(keyword), COUNT(*)
+		FROM search_events
+		WHERE searched_at >= datetime('now', '-14 days')
+		GROUP BY 1, 2`,
+	)
+}
+
+// 한글 정규화 규칙 변경(공백 무시 등)에 맞춰 정규화 키워드를 다시 계산하고,
+// 새 키로 겹치게 된 검색 기록과 급상승 버킷을 합침
+func migrateRenormalizeKeywords(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT DISTINCT keyword FROM search_events`)
+	if err != nil {
+		return err
+	}
+	var keywords []string
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			rows.Close()
+			return err
+		}
+		keywords = append(keywords, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, k := range keywords {
+		if _, err := tx.Exec(`UPDATE search_events SET normalized_keyword = ? WHERE keyword = ?`, textnorm.Key(k), k); err != nil {
+			return err
+		}
+	}
+
+	rows, err = tx.Query(`
+		SELECT id, user_id, keyword, count, result_count, first_searched_at, last_searched_at, deleted_at
+		FROM search_history
+		ORDER BY last_searched_at, id
+	`)
+	if err != nil {
+		return err
+	}
+
+	type entry struct {
+		id                 int64
+		userID, keyword    string
+		count, resultCount int
+		first, last        time.Time
+		deletedAt          sql.NullTime
+	}
+	type groupKey struct{ userID, normalized string }
+	merged := make(map[groupKey]*entry)
+	var order []groupKey
+	for rows.Next() {
+		var e entry
+		err := rows.Scan(&e.id, &e.userID, &e.keyword, &e.count, &e.resultCount, &e.first, &e.last, &e.deletedAt)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		k := groupKey{e.userID, textnorm.Key(e.keyword)}
+		if k.normalized == "" {
+			continue
+		}
+		m, ok := merged[k]
+		if !ok {
+			merged[k] = &e
+			order = append(order, k)
+			continue
+		}
+		// Rows come oldest first. A live row outranks deleted ones;
+		// live rows are summed, and the latest row's spelling wins.
+		switch {
+		case m.deletedAt.Valid && !e.deletedAt.Valid:
+			*m = e
+		case !m.deletedAt.Valid && e.deletedAt.Valid:
+		case !m.deletedAt.Valid:
+			first := m.first
+			if e.first.After(first) {
+				e.first = first
+			}
+			e.count += m.count
+			*m = e
+		default:
+			*m = e
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM search_history`); err != nil {
+		return err
+	}
+	for _, k := range order {
+		e := merged[k]
+		var deletedAt interface{}
+		if e.deletedAt.Valid {
+			deletedAt = FormatTime(e.deletedAt.Time)
+		}
+		_, err := tx.Exec(`
+			INSERT INTO search_history (id, user_id, keyword, normalized_keyword, count, result_count, first_searched_at, last_searched_at, deleted_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, e.id, e.userID, e.keyword, k.normalized, e.count, e.resultCount, FormatTime(e.first), FormatTime(e.last), deletedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return execAll(tx,
+		`DELETE FROM keyword_trend_buckets`,
+		`INSERT INTO keyword_trend_buckets (normalized_keyword, bucket_start, keyword, count)
+		SELECT normalized_keyword, (CAST(strftime('%s', searched_at) AS INTEGER) / 600) * 600, MAX(keyword), COUNT(*)
+		FROM search_events
+		WHERE searched_at >= datetime('now', '-14 days')
+		GROUP BY 1, 2`,
+	)
+}
+
+// 캐시된 검색 결과를 장소 단위로 정규화한 테이블과 전문 검색 색인.
+// FTS5 색인에는 textnorm.Bigrams로 만든 2-gram 토큰을 저장
+func migratePlaces(tx *sql.Tx) error {
+	err := execAll(tx,
+		`CREATE TABLE IF NOT EXISTS places (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			place_id TEXT NOT NULL UNIQUE,
+			place_name TEXT NOT NULL,
+			category_name TEXT,
+			category_group_code TEXT,
+			category_group_name TEXT,
+			phone TEXT,
+			address TEXT,
+			road_address TEXT,
+			lat REAL,
+			lng REAL,
+			place_url TEXT,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE VIRTUAL TABLE IF NOT EXISTS places_fts USING fts5(name, address, category)`,
+		// WHERE true disambiguates ON CONFLICT after a SELECT
+		`INSERT INTO places (place_id, place_name, category_name, category_group_code, category_group_name,
+			phone, address, road_address, lat, lng, place_url, updated_at)
+		SELECT json_extract(p.value, '$.id'), json_extract(p.value, '$.place_name'),
+			json_extract(p.value, '$.category_name'), json_extract(p.value, '$.category_group_code'),
+			json_extract(p.value, '$.category_group_name'), json_extract(p.value, '$.phone'),
+			json_extract(p.value, '$.address_name'), json_extract(p.value, '$.road_address_name'),
+			CAST(json_extract(p.value, '$.y') AS REAL), CAST(json_extract(p.value, '$.x') AS REAL),
+			json_extract(p.value, '$.place_url'), c.cached_at
+		FROM search_cache c, json_each(c.results_json) p
+		WHERE json_valid(c.results_json) AND json_extract(p.value, '$.id') <> ''
+			AND json_extract(p.value, '$.place_name') IS NOT NULL
+		ORDER BY c.cached_at
+		ON CONFLICT(place_id) DO UPDATE SET
+			place_name = excluded.place_name,
+			category_name = excluded.category_name,
+			category_group_code = excluded.category_group_code,
+			category_group_name = excluded.category_group_name,
+			phone = excluded.phone,
+			address = excluded.address,
+			road_address = excluded.road_address,
+			lat = excluded.lat,
+			lng = excluded.lng,
+			place_url = excluded.place_url,
+			updated_at = excluded.updated_at`,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`SELECT id, place_name, address, road_address, category_name FROM places`)
+	if err != nil {
+		return err
+	}
+	type doc struct {
+		id                            int64
+		name, address, road, category sql.NullString
+	}
+	var docs []doc
+	for rows.Next() {
+		var d doc
+		if err := rows.Scan(&d.id, &d.name, &d.address, &d.road, &d.category); err != nil {
+			rows.Close()
+			return err
+		}
+		docs = append(docs, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, d := range docs {
+		_, err := tx.Exec(`INSERT INTO places_fts (rowid, name, address, category) VALUES (?, ?, ?, ?)`,
+			d.id, textnorm.Bigrams(d.name.String),
+			textnorm.Bigrams(d.address.String)+" "+textnorm.Bigrams(d.road.String),
+			textnorm.Bigrams(d.category.String))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 장소 카탈로그: 최초/최근 발견 시각과 출처, 즐겨찾기 장소 편입,
+// 장소가 등장한 검색어 기록
+func migratePlaceCatalog(tx *sql.Tx) error {
+	err := execAll(tx,
+		`ALTER TABLE places ADD COLUMN first_seen DATETIME`,
+		`ALTER TABLE places ADD COLUMN last_seen DATETIME`,
+		`ALTER TABLE places ADD COLUMN source TEXT NOT NULL DEFAULT 'search'`,
+		`UPDATE places SET first_seen = updated_at, last_seen = updated_at`,
+		`CREATE TABLE IF NOT EXISTS place_appearances (
+			place_id TEXT NOT NULL,
+			normalized_keyword TEXT NOT NULL,
+			keyword TEXT NOT NULL,
+			count INTEGER NOT NULL DEFAULT 1,
+			first_seen DATETIME NOT NULL,
+			last_seen DATETIME NOT NULL,
+			PRIMARY KEY (place_id, normalized_keyword)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_place_appearances_seen ON place_appearances(place_id, last_seen)`,
+	)
+	if err != nil {
+		return err
+	}
+
+	// Cached keywords become appearances of the places they returned
+	rows, err := tx.Query(`
+		SELECT c.keyword, json_extract(p.value, '$.id'), c.cached_at
+		FROM search_cache c, json_each(c.results_json) p
+		WHERE json_valid(c.results_json) AND json_extract(p.value, '$.id') <> ''
+	`)
+	if err != nil {
+		return err
+	}
+	type appearance struct {
+		keyword, placeID string
+		seenAt           time.Time
+	}
+	var appearances []appearance
+	for rows.Next() {
+		var a appearance
+		if err := rows.Scan(&a.keyword, &a.placeID, &a.seenAt); err != nil {
+			rows.Close()
+			return err
+		}
+		appearances = append(appearances, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, a := range appearances {
+		key := textnorm.Key(a.keyword)
+		if key == "" {
+			continue
+		}
+		_, err := tx.Exec(`
+			INSERT INTO place_appearances (place_id, normalized_keyword, keyword, first_seen, last_seen)
+			VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(place_id, normalized_keyword) DO NOTHING
+		`, a.placeID, key, a.keyword, FormatTime(a.seenAt), FormatTime(a.seenAt))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Favorited places not seen in any cached search
+	rows, err = tx.Query(`
+		SELECT place_id, place_name, address, road_address, category,
+			MIN(lat), MIN(lng), MAX(phone), MIN(created_at), MAX(updated_at)
+		FROM favorites
+		WHERE place_id NOT IN (SELECT place_id FROM places)
+		GROUP BY place_id
+	`)
+	if err != nil {
+		return err
+	}
+	type place struct {
+		placeID, name, address, road, category, phone sql.NullString
+		lat, lng                                      float64
+		firstSeen, lastSeen                           string
+	}
+	var places []place
+	for rows.Next() {
+		var p place
+		err := rows.Scan(&p.placeID, &p.name, &p.address, &p.road, &p.category,
+			&p.lat, &p.lng, &p.phone, &p.firstSeen, &p.lastSeen)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		places = append(places, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, p := range places {
+		result, err := tx.Exec(`
+			INSERT INTO places (place_id, place_name, category_name, phone, address, road_address,
+				lat, lng, source, first_seen, last_seen)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, 'favorite', ?, ?)
+		`, p.placeID, p.name, p.category, p.phone, p.address, p.road, p.lat, p.lng, p.firstSeen, p.lastSeen)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO places_fts (rowid, name, address, category) VALUES (?, ?, ?, ?)`,
+			id, textnorm.Bigrams(p.name.String),
+			textnorm.Bigrams(p.address.String)+" "+textnorm.Bigrams(p.road.String),
+			textnorm.Bigrams(p.category.String))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 공개 검색어(인기/급상승/자동완성) 관리자 숨김·고정
+func migrateKeywordModeration(tx *sql.Tx) error {
+	return execAll(tx,
+		`CREATE TABLE IF NOT EXISTS keyword_moderation (
+			normalized_keyword TEXT PRIMARY KEY,
+			keyword TEXT NOT NULL,
+			status TEXT NOT NULL CHECK (status IN ('hidden', 'pinned')),
+			note TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+	)
+}
+
+// 카카오 로그인 계정과 서버 측 세션 (세션 토큰은 해시로만 저장)
+func migrateUsersSessions(tx *sql.Tx) error {
+	return execAll(tx,
+		`CREATE TABLE IF NOT EXISTS users (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			provider TEXT NOT NULL,
+			provider_user_id TEXT NOT NULL,
+			nickname TEXT,
+			profile_image_url TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			last_login_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(provider, provider_user_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS sessions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			token_hash TEXT NOT NULL UNIQUE,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			user_agent TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			last_seen_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			expires_at DATETIME NOT NULL,
+			revoked_at DATETIME
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id)`,
+	)
+}
+
+// 개인정보 삭제 요청 처리 기록. 완료 후에는 사용자 ID 대신 해시만 남김
+func migrateDeletionJobs(tx *sql.Tx) error {
+	return execAll(tx,
+		`CREATE TABLE IF NOT EXISTS deletion_jobs (
+			id TEXT PRIMARY KEY,
+			subject TEXT,
+			account_id INTEGER,
+			subject_hash TEXT NOT NULL,
+			status TEXT NOT NULL CHECK (status IN ('pending', 'running', 'done', 'failed')),
+			deleted_rows INTEGER NOT NULL DEFAULT 0,
+			anonymized_rows INTEGER NOT NULL DEFAULT 0,
+			error TEXT,
+			requested_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			started_at DATETIME,
+			finished_at DATETIME
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_deletion_jobs_status ON deletion_jobs(status)`,
+		`CREATE INDEX IF NOT EXISTS idx_deletion_jobs_subject ON deletion_jobs(subject_hash)`,
+	)
+}
+
+// 관리자 계정 표시
+func migrateUserAdminFlag(tx *sql.Tx) error {
+	return execAll(tx,
+		`ALTER TABLE users ADD COLUMN is_admin INTEGER NOT NULL DEFAULT 0`,
+	)
+}
+
+// 외부 클라이언트용 API 키 (키는 해시로만 저장)와 일별 사용량
+func migrateAPIKeys(tx *sql.Tx) error {
+	return execAll(tx,
+		`CREATE TABLE IF NOT EXISTS api_keys (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			prefix TEXT NOT NULL,
+			key_hash TEXT NOT NULL UNIQUE,
+			scopes TEXT NOT NULL,
+			rate_limit INTEGER NOT NULL,
+			daily_quota INTEGER NOT NULL,
+			created_by TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			last_used_at DATETIME,
+			revoked_at DATETIME
+		)`,
+		`CREATE TABLE IF NOT EXISTS api_key_usage (
+			key_id INTEGER NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+			day TEXT NOT NULL,
+			count INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (key_id, day)
+		)`,
+	)
+}
+
+// 관리·삭제 작업 감사 기록. 추가만 가능하고, 90일이 지난 기록만 삭제 가능
+func migrateAuditLog(tx *sql.Tx) error {
+	return execAll(tx,
+		`CREATE TABLE IF NOT EXISTS audit_log (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			actor TEXT NOT NULL,
+			ip TEXT,
+			action TEXT NOT NULL,
+			target TEXT,
+			before_json TEXT,
+			after_json TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_time ON audit_log(created_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor, created_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action, created_at)`,
+		`CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+		BEGIN
+			SELECT RAISE(ABORT, 'audit_log is append-only');
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS audit_log_retention BEFORE DELETE ON audit_log
+		WHEN old.created_at > datetime('now', '-90 days')
+		BEGIN
+			SELECT RAISE(ABORT, 'audit_log entries are kept for at least 90 days');
+		END`,
+	)
+}
+
+// 보존 기간이 지난 검색 이벤트를 합산한 검색어별 누적 횟수
+func migrateSearchKeywordTotals(tx *sql.Tx) error {
+	return execAll(tx,
+		`CREATE TABLE IF NOT EXISTS search_keyword_totals (
+			normalized_keyword TEXT PRIMARY KEY,
+			keyword TEXT NOT NULL,
+			count INTEGER NOT NULL DEFAULT 0,
+			last_searched_at DATETIME
+		)`,
+	)
+}
+
+// 서명 없는 이전 user_id 쿠키를 한 번만 받아들이기 위한 기록 (ID는 해시로 저장)
+func migrateLegacyIdentityClaims(tx *sql.Tx) error {
+	return execAll(tx,
+		`CREATE TABLE IF NOT EXISTS legacy_identity_claims (
+			id_hash TEXT PRIMARY KEY,
+			claimed_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+	)
+}
+
+// 감사 기록에 남는 사용자 가명. 계정 삭제 시 매핑을 지워 기록과의 연결을 끊음
+func migrateAuditSubjects(tx *sql.Tx) error {
+	return execAll(tx,
+		`CREATE TABLE IF NOT EXISTS audit_subjects (
+			subject TEXT PRIMARY KEY,
+			pseudonym TEXT NOT NULL UNIQUE,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+	)
+}
@@ -0,0 +1,421 @@
+package handler
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jju-compass/jju-compass-map/internal/middleware"
+	"github.com/jju-compass/jju-compass-map/internal/models"
+	"github.com/jju-compass/jju-compass-map/internal/repository"
+	"github.com/jju-compass/jju-compass-map/internal/suggest"
+	"github.com/jju-compass/jju-compass-map/internal/textnorm"
+)
+
+// AdminHandler handles operator reports and maintenance
+type AdminHandler struct {
+	reports    *repository.ReportRepository
+	moderation *repository.ModerationRepository
+	users      *repository.UserRepository
+	suggest    *suggest.Index
+	quotas     map[string]*middleware.DailyAPILimiter
+	tokens     []adminToken
+	audit      *Auditor
+}
+
+// NewAdminHandler creates a new admin handler. quotas names the daily
+// API quotas operators can inspect and reset; tokens are the configured
+// "name:token" admin bearer tokens.
+func NewAdminHandler(reports *repository.ReportRepository, moderationRepo *repository.ModerationRepository,
+	users *repository.UserRepository, suggestIndex *suggest.Index,
+	quotas map[string]*middleware.DailyAPILimiter, tokens []string, audit *Auditor) *AdminHandler {
+	return &AdminHandler{
+		reports:    reports,
+		moderation: moderationRepo,
+		users:      users,
+		suggest:    suggestIndex,
+		quotas:     quotas,
+		tokens:     parseAdminTokens(tokens),
+		audit:      audit,
+	}
+}
+
+// lowResultDefaultPeriod is the report period when "from" is not given
+const lowResultDefaultPeriod = 30 * 24 * time.Hour
+
+// GetLowResultReport lists keywords that returned few or no results,
+// pointing at places missing from Kakao
+// GET /api/admin/reports/low-results?from=2024-03-01&to=2024-03-31&max_results=2&min_searches=1&limit=50
+func (h *AdminHandler) GetLowResultReport(c *gin.Context) {
+	from, to, err := parseTimeRange(c)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	// An open-ended report runs up to now; "to" stays unbounded in the
+	// query so that searches made this very second are included
+	until := to
+	if until.IsZero() {
+		until = time.Now()
+	}
+	if from.IsZero() {
+		from = until.Add(-lowResultDefaultPeriod)
+	}
+
+	opts := repository.LowResultOptions{
+		From:        from,
+		To:          to,
+		MaxResults:  2,
+		MinSearches: 1,
+		Samples:     3,
+		Limit:       50,
+	}
+	if v := c.Query("max_results"); v != "" {
+		parsed, err := parseInt(v)
+		if err != nil {
+			BadRequest(c, "max_results must be a non-negative number")
+			return
+		}
+		opts.MaxResults = parsed
+	}
+	if v := c.Query("min_searches"); v != "" {
+		if parsed, err := parseInt(v); err == nil && parsed > 0 {
+			opts.MinSearches = parsed
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		if parsed, err := parseInt(v); err == nil && parsed > 0 && parsed <= 500 {
+			opts.Limit = parsed
+		}
+	}
+
+	report, err := h.reports.LowResultKeywords(opts)
+	if err != nil {
+		InternalError(c, "검색 결과 리포트 조회 실패")
+		return
+	}
+	if report == nil {
+		report = []models.LowResultKeyword{}
+	}
+
+	Success(c, gin.H{
+		"from":        from,
+		"to":          until,
+		"max_results": opts.MaxResults,
+		"keywords":    report,
+		"count":       len(report),
+	})
+}
+
+// GetModeratedKeywords lists hidden and pinned keywords
+// GET /api/admin/keywords
+func (h *AdminHandler) GetModeratedKeywords(c *gin.Context) {
+	list, err := h.moderation.List()
+	if err != nil {
+		InternalError(c, "검색어 관리 목록 조회 실패")
+		return
+	}
+	if list == nil {
+		list = []models.KeywordModeration{}
+	}
+
+	Success(c, gin.H{
+		"keywords": list,
+		"count":    len(list),
+	})
+}
+
+// ModerateKeyword hides or pins a public keyword
+// PUT /api/admin/keywords
+func (h *AdminHandler) ModerateKeyword(c *gin.Context) {
+	var req struct {
+		Keyword string `json:"keyword" binding:"required"`
+		Status  string `json:"status" binding:"required"`
+		Note    string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "invalid request body")
+		return
+	}
+	if req.Status != models.KeywordHidden && req.Status != models.KeywordPinned {
+		BadRequest(c, "status must be hidden or pinned")
+		return
+	}
+	if textnorm.Key(req.Keyword) == "" {
+		BadRequest(c, "keyword is required")
+		return
+	}
+
+	before, err := h.moderation.Get(req.Keyword)
+	if err != nil {
+		InternalError(c, "검색어 관리 저장 실패")
+		return
+	}
+	m, err := h.moderation.Set(req.Keyword, req.Status, req.Note)
+	if err != nil {
+		InternalError(c, "검색어 관리 저장 실패")
+		return
+	}
+	h.audit.Record(c, auditKeywordModerate, "keyword:"+m.Keyword, before, m)
+
+	// Take a hidden keyword out of autocomplete now rather than at the
+	// next rebuild
+	if m.Status == models.KeywordHidden && h.suggest != nil {
+		h.suggest.Remove(m.Keyword, suggest.KindPopular)
+	}
+
+	Success(c, m)
+}
+
+// UnmoderateKeyword removes a hide or pin. Popular and trending lists
+// show an unhidden keyword right away; autocomplete only brings it back
+// at the next rebuild of the suggestion index, within 30 minutes.
+// DELETE /api/admin/keywords?keyword=xxx
+func (h *AdminHandler) UnmoderateKeyword(c *gin.Context) {
+	keyword := c.Query("keyword")
+	if keyword == "" {
+		BadRequest(c, "keyword is required")
+		return
+	}
+
+	before, err := h.moderation.Get(keyword)
+	if err != nil {
+		InternalError(c, "검색어 관리 해제 실패")
+		return
+	}
+	err = h.moderation.Delete(keyword)
+	if errors.Is(err, repository.ErrNotFound) {
+		NotFound(c, "관리 중인 검색어가 아닙니다")
+		return
+	}
+	if err != nil {
+		InternalError(c, "검색어 관리 해제 실패")
+		return
+	}
+	h.audit.Record(c, auditKeywordUnmoderate, "keyword:"+keyword, before, nil)
+
+	if before != nil && before.Status == models.KeywordHidden {
+		SuccessMessage(c, "검색어 관리가 해제되었습니다. 자동완성에는 30분 이내에 다시 표시됩니다")
+		return
+	}
+	SuccessMessage(c, "검색어 관리가 해제되었습니다")
+}
+
+// quotaStatus reports the usage of a daily API quota
+func quotaStatus(name string, limiter *middleware.DailyAPILimiter) gin.H {
+	used, limit := limiter.GetUsage()
+	return gin.H{
+		"name":     name,
+		"used":     used,
+		"limit":    limit,
+		"reset_at": limiter.ResetAt(),
+	}
+}
+
+// GetQuotas lists today's usage of the daily API quotas
+// GET /api/admin/quotas
+func (h *AdminHandler) GetQuotas(c *gin.Context) {
+	names := make([]string, 0, len(h.quotas))
+	for name := range h.quotas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	quotas := make([]gin.H, 0, len(names))
+	for _, name := range names {
+		quotas = append(quotas, quotaStatus(name, h.quotas[name]))
+	}
+
+	Success(c, gin.H{
+		"quotas": quotas,
+		"count":  len(quotas),
+	})
+}
+
+// ResetQuota clears today's usage of a daily API quota
+// POST /api/admin/quotas/:name/reset
+func (h *AdminHandler) ResetQuota(c *gin.Context) {
+	name := c.Param("name")
+	limiter, ok := h.quotas[name]
+	if !ok {
+		NotFound(c, "할당량을 찾을 수 없습니다")
+		return
+	}
+
+	before := quotaStatus(name, limiter)
+	limiter.Reset()
+	after := quotaStatus(name, limiter)
+	h.audit.Record(c, auditQuotaReset, "quota:"+name, before, after)
+
+	Success(c, after)
+}
+
+// FindUsers looks up accounts by ID, "user_<id>" or nickname
+// GET /api/admin/users?q=xxx&limit=20
+func (h *AdminHandler) FindUsers(c *gin.Context) {
+	limit := 20
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := parseInt(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+
+	users, err := h.users.Find(c.Query("q"), limit)
+	if err != nil {
+		InternalError(c, "사용자 조회 실패")
+		return
+	}
+	if users == nil {
+		users = []models.User{}
+	}
+
+	Success(c, gin.H{
+		"users": users,
+		"count": len(users),
+	})
+}
+
+// parseAccountID reads an account ID given as a number or "user_<id>"
+func parseAccountID(s string) (int64, error) {
+	return strconv.ParseInt(strings.TrimPrefix(s, "user_"), 10, 64)
+}
+
+// GetUser returns an account with how much data is stored under it
+// GET /api/admin/users/:id
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	id, err := parseAccountID(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "invalid user id")
+		return
+	}
+
+	user, err := h.users.Lookup(id)
+	if err != nil {
+		InternalError(c, "사용자 조회 실패")
+		return
+	}
+	if user == nil {
+		NotFound(c, "사용자를 찾을 수 없습니다")
+		return
+	}
+
+	Success(c, user)
+}
+
+// SetUserAdmin grants or revokes operator access for an account
+// PUT /api/admin/users/:id/admin
+func (h *AdminHandler) SetUserAdmin(c *gin.Context) {
+	id, err := parseAccountID(c.Param("id"))
+	if err != nil {
+		BadRequest(c, "invalid user id")
+		return
+	}
+	var req struct {
+		IsAdmin *bool `json:"is_admin" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "invalid request body")
+		return
+	}
+
+	before, err := h.users.Lookup(id)
+	if err != nil {
+		InternalError(c, "사용자 조회 실패")
+		return
+	}
+	if before == nil {
+		NotFound(c, "사용자를 찾을 수 없습니다")
+		return
+	}
+
+	err = h.users.SetAdmin(id, *req.IsAdmin)
+	if errors.Is(err, repository.ErrNotFound) {
+		NotFound(c, "사용자를 찾을 수 없습니다")
+		return
+	}
+	if err != nil {
+		InternalError(c, "관리자 권한 변경 실패")
+		return
+	}
+
+	user, err := h.users.Lookup(id)
+	if err != nil {
+		InternalError(c, "사용자 조회 실패")
+		return
+	}
+	h.audit.Record(c, auditUserSetAdmin, "user:"+strconv.FormatInt(id, 10), before, user)
+	Success(c, user)
+}
+
+// GetAuditLog lists audit entries, newest first. action also takes a
+// prefix such as "favorite."; user_id finds an ordinary user's entries
+// without the log storing their ID. Page with before_id=next_before_id.
+// GET /api/admin/audit?actor=xxx&user_id=xxx&action=xxx&target=xxx&from=2024-03-01&to=2024-03-31&before_id=123&limit=50
+func (h *AdminHandler) GetAuditLog(c *gin.Context) {
+	from, to, err := parseTimeRange(c)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	opts := repository.AuditListOptions{
+		Actor:  c.Query("actor"),
+		Action: c.Query("action"),
+		Target: c.Query("target"),
+		From:   from,
+		To:     to,
+		Limit:  50,
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		if opts.Actor != "" {
+			BadRequest(c, "actor and user_id cannot be combined")
+			return
+		}
+		actor, err := h.audit.repo.FindPseudonym(userID)
+		if err != nil {
+			InternalError(c, "감사 로그 조회 실패")
+			return
+		}
+		if actor == "" {
+			// The user never had an audited action, or has been erased
+			Success(c, gin.H{"entries": []models.AuditEntry{}, "count": 0, "next_before_id": nil})
+			return
+		}
+		opts.Actor = actor
+	}
+	if b := c.Query("before_id"); b != "" {
+		id, err := strconv.ParseInt(b, 10, 64)
+		if err != nil || id <= 0 {
+			BadRequest(c, "invalid before_id")
+			return
+		}
+		opts.BeforeID = id
+	}
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := parseInt(l); err == nil && parsed > 0 && parsed <= 500 {
+			opts.Limit = parsed
+		}
+	}
+
+	entries, err := h.audit.repo.List(opts)
+	if err != nil {
+		InternalError(c, "감사 로그 조회 실패")
+		return
+	}
+	if entries == nil {
+		entries = []models.AuditEntry{}
+	}
+
+	var next interface{}
+	if len(entries) == opts.Limit {
+		next = entries[len(entries)-1].ID
+	}
+	Success(c, gin.H{
+		"entries":        entries,
+		"count":          len(entries),
+		"next_before_id": next,
+	})
+}
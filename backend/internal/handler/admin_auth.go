@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminActorKey is the context key naming who passed the admin check
+const adminActorKey = "admin_actor"
+
+// minAdminTokenLength is the shortest accepted admin token
+const minAdminTokenLength = 16
+
+// adminToken is a static admin bearer token from configuration
+type adminToken struct {
+	name string
+	hash [sha256.Size]byte
+}
+
+// parseAdminTokens reads "name:token" entries. Unnamed tokens are
+// numbered and tokens too short to be safe are ignored with a warning.
+func parseAdminTokens(entries []string) []adminToken {
+	var tokens []adminToken
+	for i, entry := range entries {
+		name, token, ok := strings.Cut(entry, ":")
+		if !ok {
+			name, token = fmt.Sprintf("token%d", i+1), entry
+		}
+		if len(token) < minAdminTokenLength {
+			log.Printf("Ignoring admin token %q: must be at least %d characters", name, minAdminTokenLength)
+			continue
+		}
+		tokens = append(tokens, adminToken{name: name, hash: sha256.Sum256([]byte(token))})
+	}
+	return tokens
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header
+func bearerToken(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+// RequireAdmin is middleware that admits a configured admin bearer token
+// or a logged-in account flagged as admin
+func (h *AdminHandler) RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := bearerToken(c); token != "" {
+			hash := sha256.Sum256([]byte(token))
+			for _, t := range h.tokens {
+				if subtle.ConstantTimeCompare(hash[:], t.hash[:]) == 1 {
+					c.Set(adminActorKey, "token:"+t.name)
+					c.Next()
+					return
+				}
+			}
+			Unauthorized(c, "관리자 인증 실패")
+			c.Abort()
+			return
+		}
+
+		_, user := currentUser(c)
+		if user == nil {
+			Unauthorized(c, "로그인이 필요합니다")
+			c.Abort()
+			return
+		}
+		if !user.IsAdmin {
+			Forbidden(c, "관리자 권한이 필요합니다")
+			c.Abort()
+			return
+		}
+		c.Set(adminActorKey, user.Subject())
+		c.Next()
+	}
+}
+
+// adminActor returns who passed the admin check, e.g. "token:ops" or "user_1"
+func adminActor(c *gin.Context) string {
+	return c.GetString(adminActorKey)
+}
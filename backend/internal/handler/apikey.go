@@ -0,0 +1,209 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jju-compass/jju-compass-map/internal/middleware"
+	"github.com/jju-compass/jju-compass-map/internal/models"
+	"github.com/jju-compass/jju-compass-map/internal/repository"
+)
+
+// apiKeyContextKey is the context key holding the authenticated API key
+const apiKeyContextKey = "api_key"
+
+// Defaults for keys issued without explicit limits
+const (
+	defaultKeyRateLimit  = 60    // requests per minute
+	defaultKeyDailyQuota = 10000 // requests per day
+)
+
+// apiKeyRoutes lists the only routes API keys may call, with the scope
+// each one needs. Everything else is reserved for the web app.
+var apiKeyRoutes = map[string]string{
+	"GET /api/places/search":         models.ScopeReadPlaces,
+	"GET /api/places/:id":            models.ScopeReadPlaces,
+	"GET /api/cache/search":          models.ScopeReadPlaces,
+	"GET /api/history/popular":       models.ScopeReadPopular,
+	"GET /api/history/trending":      models.ScopeReadPopular,
+	"GET /api/favorites":             models.ScopeWriteFavorites,
+	"POST /api/favorites":            models.ScopeWriteFavorites,
+	"DELETE /api/favorites":          models.ScopeWriteFavorites,
+	"PATCH /api/favorites/:place_id": models.ScopeWriteFavorites,
+	"GET /api/favorites/check":       models.ScopeWriteFavorites,
+	"POST /api/favorites/check":      models.ScopeWriteFavorites,
+}
+
+// APIKeyHandler authenticates third-party clients and manages their keys
+type APIKeyHandler struct {
+	keys   *repository.APIKeyRepository
+	limits *middleware.RateLimiter
+	audit  *Auditor
+}
+
+// NewAPIKeyHandler creates a new API key handler. Each key's rate limit
+// is a token bucket in limits, in place of the route policies.
+func NewAPIKeyHandler(keys *repository.APIKeyRepository, limits *middleware.RateLimiter, audit *Auditor) *APIKeyHandler {
+	return &APIKeyHandler{keys: keys, limits: limits, audit: audit}
+}
+
+// Authenticate is middleware for "Authorization: Bearer jjk_..." requests.
+// It checks the key's scope for the route and its rate limit and daily
+// quota, then makes the key the request's user, so that favorites written
+// with a key are kept under that key. Requests without an API key are
+// left to the cookie identity.
+func (h *APIKeyHandler) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := bearerToken(c)
+		if !strings.HasPrefix(token, repository.APIKeyPrefix) {
+			c.Next()
+			return
+		}
+
+		key, err := h.keys.Authenticate(token)
+		if err != nil {
+			InternalError(c, "API 키 확인 실패")
+			c.Abort()
+			return
+		}
+		if key == nil {
+			Unauthorized(c, "유효하지 않은 API 키입니다")
+			c.Abort()
+			return
+		}
+
+		scope, ok := apiKeyRoutes[c.Request.Method+" "+c.FullPath()]
+		if !ok {
+			Forbidden(c, "API 키로 사용할 수 없는 요청입니다")
+			c.Abort()
+			return
+		}
+		if !key.HasScope(scope) {
+			Forbidden(c, "API 키에 "+scope+" 권한이 없습니다")
+			c.Abort()
+			return
+		}
+
+		policy := middleware.RateLimitPolicy{Name: "api_key", Limit: key.RateLimit, Period: time.Minute, Burst: key.RateLimit}
+		if !h.limits.Allow(c, policy, key.Subject()) {
+			Error(c, http.StatusTooManyRequests, "API 키 요청 한도를 초과했습니다")
+			c.Abort()
+			return
+		}
+		middleware.MarkRateLimited(c)
+
+		used, ok, err := h.keys.RecordUse(key, time.Now().In(kst).Format("2006-01-02"))
+		if err != nil {
+			InternalError(c, "API 키 확인 실패")
+			c.Abort()
+			return
+		}
+		c.Header("X-Quota-Limit", strconv.Itoa(key.DailyQuota))
+		c.Header("X-Quota-Remaining", strconv.Itoa(key.DailyQuota-used))
+		if !ok {
+			Error(c, http.StatusTooManyRequests, "API 키의 일일 사용량을 초과했습니다")
+			c.Abort()
+			return
+		}
+
+		c.Set(apiKeyContextKey, key)
+		middleware.SetUserID(c, key.Subject())
+		c.Next()
+	}
+}
+
+// GetAPIKeys lists issued keys with today's usage
+// GET /api/admin/api-keys
+func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	keys, err := h.keys.List(time.Now().In(kst).Format("2006-01-02"))
+	if err != nil {
+		InternalError(c, "API 키 목록 조회 실패")
+		return
+	}
+	if keys == nil {
+		keys = []models.APIKey{}
+	}
+
+	Success(c, gin.H{
+		"keys":  keys,
+		"count": len(keys),
+	})
+}
+
+// IssueAPIKey creates a key. The key is in this response only.
+// POST /api/admin/api-keys
+func (h *APIKeyHandler) IssueAPIKey(c *gin.Context) {
+	var req struct {
+		Name       string   `json:"name" binding:"required"`
+		Scopes     []string `json:"scopes" binding:"required"`
+		RateLimit  int      `json:"rate_limit"`
+		DailyQuota int      `json:"daily_quota"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "invalid request body")
+		return
+	}
+	if len(req.Scopes) == 0 {
+		BadRequest(c, "scopes is required")
+		return
+	}
+	for _, scope := range req.Scopes {
+		valid := false
+		for _, s := range models.APIKeyScopes {
+			valid = valid || s == scope
+		}
+		if !valid {
+			BadRequest(c, "unknown scope: "+scope)
+			return
+		}
+	}
+	if req.RateLimit < 0 || req.DailyQuota < 0 {
+		BadRequest(c, "rate_limit and daily_quota must be positive")
+		return
+	}
+	if req.RateLimit == 0 {
+		req.RateLimit = defaultKeyRateLimit
+	}
+	if req.DailyQuota == 0 {
+		req.DailyQuota = defaultKeyDailyQuota
+	}
+
+	key, secret, err := h.keys.Issue(strings.TrimSpace(req.Name), req.Scopes, req.RateLimit, req.DailyQuota, adminActor(c))
+	if err != nil {
+		InternalError(c, "API 키 발급 실패")
+		return
+	}
+	h.audit.Record(c, auditAPIKeyIssue, "api_key:"+strconv.FormatInt(key.ID, 10), nil, key)
+
+	Created(c, gin.H{
+		"key":     secret,
+		"api_key": key,
+	})
+}
+
+// RevokeAPIKey disables a key for good
+// DELETE /api/admin/api-keys/:id
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "invalid key id")
+		return
+	}
+
+	err = h.keys.Revoke(id)
+	if errors.Is(err, repository.ErrNotFound) {
+		NotFound(c, "API 키를 찾을 수 없습니다")
+		return
+	}
+	if err != nil {
+		InternalError(c, "API 키 폐기 실패")
+		return
+	}
+	h.audit.Record(c, auditAPIKeyRevoke, "api_key:"+c.Param("id"), nil, nil)
+
+	SuccessMessage(c, "API 키가 폐기되었습니다")
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jju-compass/jju-compass-map/internal/models"
+	"github.com/jju-compass/jju-compass-map/internal/repository"
+)
+
+// Audited actions
+const (
+	auditCacheDelete        = "cache.delete"
+	auditCachePurgeExpired  = "cache.purge_expired"
+	auditQuotaReset         = "quota.reset"
+	auditKeywordModerate    = "keyword.moderate"
+	auditKeywordUnmoderate  = "keyword.unmoderate"
+	auditUserSetAdmin       = "user.set_admin"
+	auditAPIKeyIssue        = "api_key.issue"
+	auditAPIKeyRevoke       = "api_key.revoke"
+	auditFavoriteDelete     = "favorite.delete"
+	auditFavoriteBulk       = "favorite.bulk"
+	auditHistoryDelete      = "history.delete"
+	auditHistoryClear       = "history.clear"
+	auditListRevoke         = "list.revoke"
+	auditAccountDeleteQueue = "account.delete_requested"
+)
+
+// Auditor writes audit log entries for admin, delete and bulk actions
+type Auditor struct {
+	repo *repository.AuditRepository
+}
+
+// NewAuditor creates a new auditor
+func NewAuditor(repo *repository.AuditRepository) *Auditor {
+	return &Auditor{repo: repo}
+}
+
+// subject is how an ordinary user appears in the audit log. The log
+// cannot be edited, so it holds a random pseudonym whose mapping account
+// deletion erases; API keys are not personal and keep their ID.
+func (a *Auditor) subject(userID string) (string, error) {
+	if userID == "" || strings.HasPrefix(userID, "key_") {
+		return userID, nil
+	}
+	return a.repo.Pseudonym(userID)
+}
+
+// Record appends an entry for an action on target, with snapshots of the
+// target before and after it. Admin entries name the admin and keep the
+// client IP; user entries keep neither, so callers must leave content the
+// user typed out of target and the snapshots. A failure to record is
+// logged; the action itself has already happened.
+func (a *Auditor) Record(c *gin.Context, action, target string, before, after interface{}) {
+	if a == nil {
+		return
+	}
+	entry := &models.AuditEntry{
+		Actor:  adminActor(c),
+		Action: action,
+		Target: target,
+		Before: auditSnapshot(before),
+		After:  auditSnapshot(after),
+	}
+	if entry.Actor != "" {
+		entry.IP = c.ClientIP()
+	} else {
+		actor, err := a.subject(GetUserID(c))
+		if err != nil {
+			log.Printf("Failed to record audit entry %s %s: %v", action, target, err)
+			return
+		}
+		entry.Actor = actor
+	}
+	if err := a.repo.Record(entry); err != nil {
+		log.Printf("Failed to record audit entry %s %s: %v", action, target, err)
+	}
+}
+
+// auditSnapshot encodes a snapshot, leaving out missing ones
+func auditSnapshot(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil || string(b) == "null" {
+		return nil
+	}
+	return b
+}
@@ -0,0 +1,303 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jju-compass/jju-compass-map/internal/auth"
+	"github.com/jju-compass/jju-compass-map/internal/config"
+	"github.com/jju-compass/jju-compass-map/internal/middleware"
+	"github.com/jju-compass/jju-compass-map/internal/models"
+	"github.com/jju-compass/jju-compass-map/internal/repository"
+)
+
+// Context keys set by AuthHandler.Session for logged-in requests
+const (
+	sessionKey = "session"
+	userKey    = "user"
+)
+
+// oauthStateCookie carries the OAuth state and post-login redirect
+const oauthStateCookie = "jjk_oauth_state"
+
+// oauthStateTTL is how long a login attempt may take
+const oauthStateTTL = 10 * time.Minute
+
+// AuthHandler handles Kakao login and session management
+type AuthHandler struct {
+	users  *repository.UserRepository
+	kakao  *auth.KakaoClient
+	cfg    *config.AuthConfig
+	secure bool
+}
+
+// NewAuthHandler creates a new auth handler
+func NewAuthHandler(users *repository.UserRepository, kakao *auth.KakaoClient, cfg *config.AuthConfig, secureCookies bool) *AuthHandler {
+	return &AuthHandler{users: users, kakao: kakao, cfg: cfg, secure: secureCookies}
+}
+
+// Session is middleware that authenticates the session cookie. For a
+// logged-in request the account replaces the anonymous identity, so
+// GetUserID returns the account's user ID.
+func (h *AuthHandler) Session() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, err := c.Cookie(h.cfg.SessionCookieName)
+		if err != nil || token == "" {
+			c.Next()
+			return
+		}
+
+		session, user, err := h.users.Authenticate(token)
+		if err != nil {
+			InternalError(c, "세션 확인 실패")
+			c.Abort()
+			return
+		}
+		if session == nil {
+			// Expired or revoked elsewhere
+			h.clearSessionCookie(c)
+			c.Next()
+			return
+		}
+
+		c.Set(sessionKey, session)
+		c.Set(userKey, user)
+		middleware.SetUserID(c, user.Subject())
+		c.Next()
+	}
+}
+
+// currentUser returns the logged-in session and user, or nils
+func currentUser(c *gin.Context) (*models.Session, *models.User) {
+	session, _ := c.Get(sessionKey)
+	user, _ := c.Get(userKey)
+	s, _ := session.(*models.Session)
+	u, _ := user.(*models.User)
+	return s, u
+}
+
+// KakaoLogin starts the Kakao login flow
+// GET /api/auth/kakao/login?redirect=/map
+func (h *AuthHandler) KakaoLogin(c *gin.Context) {
+	if !h.kakao.Enabled() {
+		Error(c, http.StatusServiceUnavailable, "카카오 로그인이 설정되지 않았습니다")
+		return
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		InternalError(c, "로그인 시작 실패")
+		return
+	}
+	state := base64.RawURLEncoding.EncodeToString(b)
+	redirect := safeRedirect(c.Query("redirect"))
+
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state + "." + base64.RawURLEncoding.EncodeToString([]byte(redirect)),
+		Path:     "/api/auth",
+		MaxAge:   int(oauthStateTTL / time.Second),
+		Secure:   h.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	c.Redirect(http.StatusFound, h.kakao.AuthCodeURL(state))
+}
+
+// KakaoCallback completes the Kakao login flow, starts a session and
+// returns to the page the login started from
+// GET /api/auth/kakao/callback?code=xxx&state=xxx
+func (h *AuthHandler) KakaoCallback(c *gin.Context) {
+	cookie, err := c.Cookie(oauthStateCookie)
+	if err != nil {
+		BadRequest(c, "로그인 요청이 만료되었습니다")
+		return
+	}
+	http.SetCookie(c.Writer, &http.Cookie{Name: oauthStateCookie, Path: "/api/auth", MaxAge: -1})
+
+	state, encodedRedirect, _ := strings.Cut(cookie, ".")
+	redirect := "/"
+	if raw, err := base64.RawURLEncoding.DecodeString(encodedRedirect); err == nil {
+		redirect = safeRedirect(string(raw))
+	}
+	if state == "" || c.Query("state") != state {
+		BadRequest(c, "잘못된 로그인 요청입니다")
+		return
+	}
+
+	// The user declined on the Kakao consent screen
+	if c.Query("error") != "" || c.Query("code") == "" {
+		c.Redirect(http.StatusFound, redirect)
+		return
+	}
+
+	profile, err := h.kakao.Authenticate(c.Request.Context(), c.Query("code"))
+	if err != nil {
+		log.Printf("Kakao login failed: %v", err)
+		Error(c, http.StatusBadGateway, "카카오 로그인 실패")
+		return
+	}
+
+	user, err := h.users.Login(profile.Provider, profile.ProviderUserID, profile.Nickname, profile.ProfileImageURL)
+	if err != nil {
+		InternalError(c, "로그인 처리 실패")
+		return
+	}
+	// Data collected before logging in follows the student into the
+	// account. A failed merge doesn't block the login; it can be retried
+	// through POST /api/auth/merge.
+	h.mergeAnonymous(c, user)
+
+	token, err := h.users.CreateSession(user.ID, c.Request.UserAgent(), h.cfg.SessionTTL)
+	if err != nil {
+		InternalError(c, "로그인 처리 실패")
+		return
+	}
+
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     h.cfg.SessionCookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(h.cfg.SessionTTL / time.Second),
+		Secure:   h.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	c.Redirect(http.StatusFound, redirect)
+}
+
+// MergeAnonymous moves the data of the browser's anonymous identity into
+// the logged-in account and reports what moved. Merging again is a no-op.
+// POST /api/auth/merge
+func (h *AuthHandler) MergeAnonymous(c *gin.Context) {
+	_, user := currentUser(c)
+	if user == nil {
+		Unauthorized(c, "로그인이 필요합니다")
+		return
+	}
+
+	summary, err := h.mergeAnonymous(c, user)
+	if err != nil {
+		InternalError(c, "데이터 병합 실패")
+		return
+	}
+
+	Success(c, gin.H{
+		"summary": summary,
+	})
+}
+
+// mergeAnonymous merges the request's anonymous identity into user
+func (h *AuthHandler) mergeAnonymous(c *gin.Context, user *models.User) (*models.MergeSummary, error) {
+	anonymousID := middleware.AnonymousID(c)
+	if !middleware.IsAnonymous(anonymousID) {
+		return &models.MergeSummary{}, nil
+	}
+
+	summary, err := h.users.MergeAnonymous(anonymousID, user.Subject())
+	if err != nil {
+		log.Printf("Failed to merge %s into user %d: %v", anonymousID, user.ID, err)
+		return nil, err
+	}
+	if !summary.Empty() {
+		log.Printf("Merged %s into user %d: %+v", anonymousID, user.ID, *summary)
+	}
+	return summary, nil
+}
+
+// GetMe returns the logged-in account, if any
+// GET /api/auth/me
+func (h *AuthHandler) GetMe(c *gin.Context) {
+	_, user := currentUser(c)
+	Success(c, gin.H{
+		"authenticated": user != nil,
+		"user":          user,
+		"login_enabled": h.kakao.Enabled(),
+	})
+}
+
+// GetSessions lists the devices the account is logged in on
+// GET /api/auth/sessions
+func (h *AuthHandler) GetSessions(c *gin.Context) {
+	current, user := currentUser(c)
+	if user == nil {
+		Unauthorized(c, "로그인이 필요합니다")
+		return
+	}
+
+	sessions, err := h.users.Sessions(user.ID)
+	if err != nil {
+		InternalError(c, "세션 목록 조회 실패")
+		return
+	}
+	if sessions == nil {
+		sessions = []models.Session{}
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current.ID
+	}
+
+	Success(c, gin.H{
+		"sessions": sessions,
+		"count":    len(sessions),
+	})
+}
+
+// Logout ends the current session
+// POST /api/auth/logout
+func (h *AuthHandler) Logout(c *gin.Context) {
+	if session, _ := currentUser(c); session != nil {
+		if err := h.users.RevokeSession(session.ID); err != nil {
+			InternalError(c, "로그아웃 실패")
+			return
+		}
+	}
+	h.clearSessionCookie(c)
+	SuccessMessage(c, "로그아웃되었습니다")
+}
+
+// LogoutAll ends every session of the account, on all devices
+// POST /api/auth/logout-all
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	_, user := currentUser(c)
+	if user == nil {
+		Unauthorized(c, "로그인이 필요합니다")
+		return
+	}
+
+	revoked, err := h.users.RevokeAllSessions(user.ID)
+	if err != nil {
+		InternalError(c, "로그아웃 실패")
+		return
+	}
+	h.clearSessionCookie(c)
+
+	Success(c, gin.H{
+		"revoked": revoked,
+	})
+}
+
+// clearSessionCookie removes the session cookie from the browser
+func (h *AuthHandler) clearSessionCookie(c *gin.Context) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     h.cfg.SessionCookieName,
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   h.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// safeRedirect keeps post-login redirects on this site
+func safeRedirect(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+	return path
+}
@@ -0,0 +1,334 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jju-compass/jju-compass-map/internal/auth"
+	"github.com/jju-compass/jju-compass-map/internal/config"
+	"github.com/jju-compass/jju-compass-map/internal/database"
+	"github.com/jju-compass/jju-compass-map/internal/middleware"
+	"github.com/jju-compass/jju-compass-map/internal/repository"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// fakeKakaoUserID is the account the fake provider logs everyone in as
+const fakeKakaoUserID = 4242
+
+// newFakeKakao serves the Kakao token and user info endpoints. Code
+// "good" is the only one it accepts.
+func newFakeKakao(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/oauth/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.FormValue("code") != "good" || r.FormValue("client_id") != "test-client" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "fake-token"})
+	})
+	mux.HandleFunc("/v2/user/me", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer fake-token" {
+			http.Error(w, `{"msg":"unauthorized"}`, http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id": fakeKakaoUserID,
+			"kakao_account": map[string]interface{}{
+				"profile": map[string]string{"nickname": "학생"},
+			},
+		})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// authTest is a server with the auth routes, backed by a fresh database
+// and the fake Kakao provider
+type authTest struct {
+	t      *testing.T
+	router *gin.Engine
+	users  *repository.UserRepository
+	cfg    *config.AuthConfig
+}
+
+func newAuthTest(t *testing.T) *authTest {
+	t.Helper()
+	if err := database.Connect(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := database.InitSchema(); err != nil {
+		t.Fatal(err)
+	}
+
+	kakao := newFakeKakao(t)
+	cfg := &config.AuthConfig{
+		KakaoClientID:     "test-client",
+		KakaoRedirectURL:  "http://localhost/api/auth/kakao/callback",
+		KakaoAuthURL:      kakao.URL + "/oauth/authorize",
+		KakaoTokenURL:     kakao.URL + "/oauth/token",
+		KakaoUserInfoURL:  kakao.URL + "/v2/user/me",
+		SessionCookieName: "jjk_session",
+		SessionTTL:        time.Hour,
+	}
+	identity, err := middleware.NewIdentity(&config.IdentityConfig{
+		Keys:       []string{"k1:0123456789abcdefghij"},
+		CookieName: "jjk_uid",
+		MaxAge:     time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := repository.NewUserRepository(database.DB)
+	h := NewAuthHandler(users, auth.NewKakaoClient(cfg), cfg, false)
+	router := gin.New()
+	api := router.Group("/api", identity.Middleware(), h.Session())
+	api.GET("/auth/kakao/login", h.KakaoLogin)
+	api.GET("/auth/kakao/callback", h.KakaoCallback)
+	api.GET("/auth/me", h.GetMe)
+	api.POST("/auth/logout-all", h.LogoutAll)
+
+	return &authTest{t: t, router: router, users: users, cfg: cfg}
+}
+
+// browser keeps cookies across requests like a browser would
+type browser struct {
+	cookies map[string]string
+}
+
+func newBrowser() *browser {
+	return &browser{cookies: make(map[string]string)}
+}
+
+func (b *browser) do(at *authTest, method, target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for name, value := range b.cookies {
+		req.AddCookie(&http.Cookie{Name: name, Value: value})
+	}
+	w := httptest.NewRecorder()
+	at.router.ServeHTTP(w, req)
+
+	for _, c := range w.Result().Cookies() {
+		if c.MaxAge < 0 {
+			delete(b.cookies, c.Name)
+		} else {
+			b.cookies[c.Name] = c.Value
+		}
+	}
+	return w
+}
+
+// startLogin begins the login flow and returns the state Kakao would
+// send back
+func (b *browser) startLogin(at *authTest, redirect string) string {
+	at.t.Helper()
+	w := b.do(at, http.MethodGet, "/api/auth/kakao/login?redirect="+url.QueryEscape(redirect))
+	if w.Code != http.StatusFound {
+		at.t.Fatalf("login: status %d", w.Code)
+	}
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		at.t.Fatal(err)
+	}
+	return location.Query().Get("state")
+}
+
+// countRows counts the rows a query matches
+func (at *authTest) countRows(query string, args ...interface{}) int {
+	at.t.Helper()
+	var n int
+	if err := database.DB.QueryRow(query, args...).Scan(&n); err != nil {
+		at.t.Fatal(err)
+	}
+	return n
+}
+
+func TestSafeRedirect(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"/map", "/map"},
+		{"/map?tab=favorites", "/map?tab=favorites"},
+		{"", "/"},
+		{"map", "/"},
+		{"//evil.example", "/"},
+		{"/\\evil.example", "/"},
+		{"https://evil.example/map", "/"},
+		{"javascript:alert(1)", "/"},
+	}
+	for _, tt := range tests {
+		if got := safeRedirect(tt.in); got != tt.want {
+			t.Errorf("safeRedirect(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestKakaoLoginKeepsRedirectOnSite(t *testing.T) {
+	at := newAuthTest(t)
+	b := newBrowser()
+
+	state := b.startLogin(at, "//evil.example/phish")
+	if state == "" {
+		t.Fatal("login URL has no state")
+	}
+	cookieState, encoded, _ := strings.Cut(b.cookies[oauthStateCookie], ".")
+	if cookieState != state {
+		t.Fatalf("state cookie %q does not match state %q", cookieState, state)
+	}
+	if raw, _ := base64.RawURLEncoding.DecodeString(encoded); string(raw) != "/" {
+		t.Fatalf("stored redirect %q, want /", raw)
+	}
+
+	w := b.do(at, http.MethodGet, "/api/auth/kakao/callback?code=good&state="+state)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/" {
+		t.Fatalf("callback: status %d, Location %q; want 302 to /", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestKakaoCallbackRejectsBadState(t *testing.T) {
+	tests := []struct {
+		name  string
+		login bool
+		state string
+	}{
+		{name: "state mismatch", login: true, state: "forged"},
+		{name: "missing state", login: true, state: ""},
+		{name: "no login in progress", login: false, state: "forged"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at := newAuthTest(t)
+			b := newBrowser()
+			if tt.login {
+				b.startLogin(at, "/map")
+			}
+
+			w := b.do(at, http.MethodGet, "/api/auth/kakao/callback?code=good&state="+tt.state)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status %d, want 400", w.Code)
+			}
+			if _, ok := b.cookies[at.cfg.SessionCookieName]; ok {
+				t.Fatal("a session cookie was set")
+			}
+			if _, ok := b.cookies[oauthStateCookie]; ok {
+				t.Fatal("the state cookie was kept for another try")
+			}
+			if n := at.countRows("SELECT COUNT(*) FROM users"); n != 0 {
+				t.Fatalf("%d accounts created", n)
+			}
+		})
+	}
+}
+
+func TestKakaoCallbackProviderFailure(t *testing.T) {
+	at := newAuthTest(t)
+	b := newBrowser()
+
+	state := b.startLogin(at, "/map")
+	w := b.do(at, http.MethodGet, "/api/auth/kakao/callback?code=bad&state="+state)
+	if w.Code != http.StatusBadGateway {
+		t.Fatalf("status %d, want 502", w.Code)
+	}
+	if _, ok := b.cookies[at.cfg.SessionCookieName]; ok {
+		t.Fatal("a session cookie was set")
+	}
+}
+
+func TestKakaoCallbackStartsSessionAndMergesAnonymousData(t *testing.T) {
+	at := newAuthTest(t)
+	b := newBrowser()
+
+	// Favorite a place before logging in
+	b.do(at, http.MethodGet, "/api/auth/me")
+	anonymousID, _, _ := strings.Cut(b.cookies["jjk_uid"], ".")
+	if !middleware.IsAnonymous(anonymousID) {
+		t.Fatalf("no anonymous identity issued: %q", anonymousID)
+	}
+	_, err := database.DB.Exec(`
+		INSERT INTO favorites (user_id, place_id, place_name, lat, lng, updated_at)
+		VALUES (?, 'p1', '카페', 35.8, 127.1, CURRENT_TIMESTAMP)
+	`, anonymousID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := b.startLogin(at, "/map")
+	w := b.do(at, http.MethodGet, "/api/auth/kakao/callback?code=good&state="+state)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "/map" {
+		t.Fatalf("callback: status %d, Location %q; want 302 to /map", w.Code, w.Header().Get("Location"))
+	}
+	if b.cookies[at.cfg.SessionCookieName] == "" {
+		t.Fatal("no session cookie")
+	}
+
+	var me struct {
+		Data struct {
+			Authenticated bool `json:"authenticated"`
+			User          struct {
+				ID       int64  `json:"id"`
+				Nickname string `json:"nickname"`
+			} `json:"user"`
+		} `json:"data"`
+	}
+	w = b.do(at, http.MethodGet, "/api/auth/me")
+	if err := json.Unmarshal(w.Body.Bytes(), &me); err != nil {
+		t.Fatal(err)
+	}
+	if !me.Data.Authenticated || me.Data.User.Nickname != "학생" {
+		t.Fatalf("GET /api/auth/me = %s", w.Body.String())
+	}
+
+	subject := fmt.Sprintf("user_%d", me.Data.User.ID)
+	if n := at.countRows("SELECT COUNT(*) FROM favorites WHERE user_id = ?", subject); n != 1 {
+		t.Fatalf("%d favorites moved to the account, want 1", n)
+	}
+	if n := at.countRows("SELECT COUNT(*) FROM favorites WHERE user_id = ?", anonymousID); n != 0 {
+		t.Fatalf("%d favorites left under the anonymous identity", n)
+	}
+}
+
+func TestLogoutAllEndsEverySession(t *testing.T) {
+	at := newAuthTest(t)
+	phone, laptop := newBrowser(), newBrowser()
+	for _, b := range []*browser{phone, laptop} {
+		state := b.startLogin(at, "/")
+		if w := b.do(at, http.MethodGet, "/api/auth/kakao/callback?code=good&state="+state); w.Code != http.StatusFound {
+			t.Fatalf("callback: status %d", w.Code)
+		}
+	}
+	if n := at.countRows("SELECT COUNT(*) FROM users"); n != 1 {
+		t.Fatalf("%d accounts, want 1 for both logins", n)
+	}
+
+	w := phone.do(at, http.MethodPost, "/api/auth/logout-all")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"revoked":2`) {
+		t.Fatalf("logout-all: status %d, body %s", w.Code, w.Body.String())
+	}
+	if _, ok := phone.cookies[at.cfg.SessionCookieName]; ok {
+		t.Fatal("session cookie kept after logout-all")
+	}
+
+	// The other device's session no longer authenticates
+	w = laptop.do(at, http.MethodGet, "/api/auth/me")
+	if !strings.Contains(w.Body.String(), `"authenticated":false`) {
+		t.Fatalf("other device still logged in: %s", w.Body.String())
+	}
+	if w := laptop.do(at, http.MethodPost, "/api/auth/logout-all"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("logout-all without a session: status %d, want 401", w.Code)
+	}
+}
@@ -13,11 +13,12 @@ import (
 type CacheHandler struct {
 	repo        *repository.CacheRepository
 	historyRepo *repository.HistoryRepository
+	audit       *Auditor
 }
 
 // NewCacheHandler creates a new cache handler
-func NewCacheHandler(repo *repository.CacheRepository, historyRepo *repository.HistoryRepository) *CacheHandler {
-	return &CacheHandler{repo: repo, historyRepo: historyRepo}
+func NewCacheHandler(repo *repository.CacheRepository, historyRepo *repository.HistoryRepository, audit *Auditor) *CacheHandler {
+	return &CacheHandler{repo: repo, historyRepo: historyRepo, audit: audit}
 }
 
 // GetSearchCache retrieves cached search results
@@ -88,11 +89,14 @@ func (h *CacheHandler) SetSearchCache(c *gin.Context) {
 		_ = h.historyRepo.Add(userID, req.Keyword, len(req.Results))
 	}
 
+	// 요청 본문은 검증되지 않은 입력이므로 자동완성 색인에는 바로 넣지 않고,
+	// 사용자별 상한과 차단 규칙을 거치는 주기적 재색인에서만 반영
+
 	SuccessMessage(c, "캐시가 저장되었습니다")
 }
 
 // GetCacheStats returns cache statistics
-// GET /api/cache/stats
+// GET /api/admin/cache/stats
 func (h *CacheHandler) GetCacheStats(c *gin.Context) {
 	total, valid, err := h.repo.GetStats()
 	if err != nil {
@@ -108,16 +112,22 @@ func (h *CacheHandler) GetCacheStats(c *gin.Context) {
 }
 
 // DeleteCache deletes cache entries
-// DELETE /api/cache
+// DELETE /api/admin/cache?keyword=xxx
 func (h *CacheHandler) DeleteCache(c *gin.Context) {
 	keyword := c.Query("keyword")
 
 	if keyword != "" {
 		// Delete specific keyword
+		before, err := h.repo.Get(keyword)
+		if err != nil {
+			InternalError(c, "캐시 삭제 실패")
+			return
+		}
 		if err := h.repo.Delete(keyword); err != nil {
 			InternalError(c, "캐시 삭제 실패")
 			return
 		}
+		h.audit.Record(c, auditCacheDelete, "cache:"+keyword, before, nil)
 		SuccessMessage(c, "캐시가 삭제되었습니다")
 		return
 	}
@@ -128,6 +138,7 @@ func (h *CacheHandler) DeleteCache(c *gin.Context) {
 		InternalError(c, "만료된 캐시 삭제 실패")
 		return
 	}
+	h.audit.Record(c, auditCachePurgeExpired, "cache", nil, gin.H{"deleted": deleted})
 
 	Success(c, gin.H{
 		"deleted": deleted,
@@ -1,27 +1,77 @@
 package handler
 
 import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
+	"github.com/jju-compass/jju-compass-map/internal/geo"
 	"github.com/jju-compass/jju-compass-map/internal/models"
+	"github.com/jju-compass/jju-compass-map/internal/placesource"
 	"github.com/jju-compass/jju-compass-map/internal/repository"
 )
 
 // FavoriteHandler handles favorite-related requests
 type FavoriteHandler struct {
-	repo *repository.FavoriteRepository
+	repo          *repository.FavoriteRepository
+	verifications *repository.VerificationRepository
+	visits        *repository.VisitRepository
+	undoWindow    time.Duration
+	audit         *Auditor
 }
 
 // NewFavoriteHandler creates a new favorite handler
-func NewFavoriteHandler(repo *repository.FavoriteRepository) *FavoriteHandler {
-	return &FavoriteHandler{repo: repo}
+func NewFavoriteHandler(repo *repository.FavoriteRepository, verifications *repository.VerificationRepository, visits *repository.VisitRepository, undoWindow time.Duration, audit *Auditor) *FavoriteHandler {
+	return &FavoriteHandler{repo: repo, verifications: verifications, visits: visits, undoWindow: undoWindow, audit: audit}
 }
 
-// GetFavorites retrieves all favorites for a user
-// GET /api/favorites
+// GetFavorites retrieves favorites for a user
+// GET /api/favorites?near=lat,lng&within=500&category=xxx&q=xxx&limit=20&cursor=xxx
 func (h *FavoriteHandler) GetFavorites(c *gin.Context) {
 	userID := GetUserID(c)
 
-	favorites, err := h.repo.GetAll(userID)
+	opts := repository.FavoriteListOptions{
+		Category: strings.TrimSpace(c.Query("category")),
+		Query:    strings.TrimSpace(c.Query("q")),
+		Cursor:   c.Query("cursor"),
+	}
+
+	if near := c.Query("near"); near != "" {
+		point, err := geo.ParseLatLng(near)
+		if err != nil {
+			BadRequest(c, "near must be lat,lng")
+			return
+		}
+		opts.Near = &point
+	}
+
+	if within := c.Query("within"); within != "" {
+		meters, err := strconv.ParseFloat(within, 64)
+		if err != nil || meters <= 0 {
+			BadRequest(c, "within must be a positive number of meters")
+			return
+		}
+		if opts.Near == nil {
+			BadRequest(c, "within requires near")
+			return
+		}
+		opts.Within = meters
+	}
+
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := parseInt(l); err == nil && parsed > 0 && parsed <= 100 {
+			opts.Limit = parsed
+		}
+	}
+
+	favorites, next, err := h.repo.List(userID, opts)
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		BadRequest(c, "invalid cursor")
+		return
+	}
 	if err != nil {
 		InternalError(c, "즐겨찾기 조회 실패")
 		return
@@ -31,12 +81,60 @@ func (h *FavoriteHandler) GetFavorites(c *gin.Context) {
 		favorites = []models.Favorite{}
 	}
 
+	// Flag favorites whose place has closed, moved or changed
+	if err := h.attachFlags(favorites); err != nil {
+		InternalError(c, "즐겨찾기 검증 정보 조회 실패")
+		return
+	}
+
+	if err := h.attachVisits(userID, favorites); err != nil {
+		InternalError(c, "방문 기록 조회 실패")
+		return
+	}
+
 	Success(c, gin.H{
-		"favorites": favorites,
-		"count":     len(favorites),
+		"favorites":   favorites,
+		"count":       len(favorites),
+		"next_cursor": next,
 	})
 }
 
+// attachFlags sets Flag on favorites that differ from their latest verification
+func (h *FavoriteHandler) attachFlags(favorites []models.Favorite) error {
+	placeIDs := make([]string, len(favorites))
+	for i := range favorites {
+		placeIDs[i] = favorites[i].PlaceID
+	}
+	verifications, err := h.verifications.GetMany(placeIDs)
+	if err != nil {
+		return err
+	}
+	for i := range favorites {
+		favorites[i].Flag = placesource.Compare(&favorites[i], verifications[favorites[i].PlaceID])
+	}
+	return nil
+}
+
+// attachVisits sets visit counts and last visit times on favorites
+func (h *FavoriteHandler) attachVisits(userID string, favorites []models.Favorite) error {
+	placeIDs := make([]string, len(favorites))
+	for i := range favorites {
+		placeIDs[i] = favorites[i].PlaceID
+	}
+	summaries, err := h.visits.Summaries(userID, placeIDs)
+	if err != nil {
+		return err
+	}
+	for i := range favorites {
+		if s, ok := summaries[favorites[i].PlaceID]; ok {
+			favorites[i].VisitCount = s.Count
+			last := s.LastVisitedAt
+			favorites[i].LastVisitedAt = &last
+		}
+	}
+	return nil
+}
+
 // AddFavorite adds a new favorite
 // POST /api/favorites
 func (h *FavoriteHandler) AddFavorite(c *gin.Context) {
@@ -82,6 +180,10 @@ func (h *FavoriteHandler) AddFavorite(c *gin.Context) {
 	}
 
 	if err := h.repo.Add(favorite); err != nil {
+		if errors.Is(err, repository.ErrAlreadyExists) {
+			BadRequest(c, "이미 즐겨찾기에 추가된 장소입니다")
+			return
+		}
 		InternalError(c, "즐겨찾기 추가 실패")
 		return
 	}
@@ -100,14 +202,146 @@ func (h *FavoriteHandler) DeleteFavorite(c *gin.Context) {
 		return
 	}
 
-	if err := h.repo.Delete(userID, placeID); err != nil {
+	before, err := h.repo.Get(userID, placeID)
+	if err != nil {
 		InternalError(c, "즐겨찾기 삭제 실패")
 		return
 	}
+	err = h.repo.Delete(userID, placeID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		InternalError(c, "즐겨찾기 삭제 실패")
+		return
+	}
+	if err == nil && before != nil {
+		// Names and addresses come from the client, so only the version is kept
+		h.audit.Record(c, auditFavoriteDelete, "favorite:"+placeID, gin.H{"version": before.Version}, nil)
+	}
 
 	SuccessMessage(c, "즐겨찾기가 삭제되었습니다")
 }
 
+// RestoreFavorite undoes a recent favorite deletion
+// POST /api/favorites/restore
+// Without place_id the most recent deletion is restored.
+func (h *FavoriteHandler) RestoreFavorite(c *gin.Context) {
+	userID := GetUserID(c)
+
+	var req struct {
+		PlaceID string `json:"place_id"`
+	}
+	// An empty body is allowed
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BadRequest(c, "invalid request body")
+			return
+		}
+	}
+
+	restored, err := h.repo.Restore(userID, req.PlaceID, time.Now().Add(-h.undoWindow))
+	if err != nil {
+		InternalError(c, "즐겨찾기 복원 실패")
+		return
+	}
+	if restored == 0 {
+		NotFound(c, "복원할 즐겨찾기가 없습니다")
+		return
+	}
+
+	Success(c, gin.H{
+		"restored": restored,
+	})
+}
+
+// UpdateFavorite partially updates a favorite
+// PATCH /api/favorites/:place_id
+// The expected version is taken from the If-Match header or the "version" field.
+func (h *FavoriteHandler) UpdateFavorite(c *gin.Context) {
+	userID := GetUserID(c)
+	placeID := c.Param("place_id")
+
+	var req struct {
+		models.FavoritePatch
+		Version *int64 `json:"version"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "invalid request body")
+		return
+	}
+	if req.FavoritePatch.IsEmpty() {
+		BadRequest(c, "no fields to update")
+		return
+	}
+	if req.PlaceName != nil && strings.TrimSpace(*req.PlaceName) == "" {
+		BadRequest(c, "place_name cannot be empty")
+		return
+	}
+
+	ifMatch := strings.TrimSpace(c.GetHeader("If-Match"))
+	version, ok := parseIfMatch(ifMatch)
+	if !ok {
+		BadRequest(c, "invalid If-Match header")
+		return
+	}
+	if version == 0 && req.Version != nil {
+		version = *req.Version
+	}
+	if version == 0 && ifMatch != "*" {
+		Error(c, http.StatusPreconditionRequired, "version or If-Match is required")
+		return
+	}
+	if version == 0 {
+		// If-Match: * updates whatever version is current
+		current, err := h.repo.Get(userID, placeID)
+		if err != nil {
+			InternalError(c, "즐겨찾기 조회 실패")
+			return
+		}
+		if current == nil {
+			NotFound(c, "즐겨찾기를 찾을 수 없습니다")
+			return
+		}
+		version = current.Version
+	}
+
+	favorite, err := h.repo.Update(userID, placeID, &req.FavoritePatch, version)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		NotFound(c, "즐겨찾기를 찾을 수 없습니다")
+		return
+	case errors.Is(err, repository.ErrVersionConflict):
+		setETag(c, favorite.Version)
+		Conflict(c, "다른 곳에서 먼저 수정되었습니다", favorite)
+		return
+	case err != nil:
+		InternalError(c, "즐겨찾기 수정 실패")
+		return
+	}
+
+	setETag(c, favorite.Version)
+	Success(c, favorite)
+}
+
+// setETag sets the ETag header for a favorite version
+func setETag(c *gin.Context, version int64) {
+	c.Header("ETag", `"`+strconv.FormatInt(version, 10)+`"`)
+}
+
+// parseIfMatch extracts a version from an If-Match header.
+// An empty header or "*" yields version 0.
+func parseIfMatch(header string) (int64, bool) {
+	if header == "" || header == "*" {
+		return 0, true
+	}
+	header = strings.TrimPrefix(header, "W/")
+	header = strings.Trim(header, `"`)
+	version, err := strconv.ParseInt(header, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+	return version, true
+}
+
 // CheckFavorite checks if a place is favorited
 // GET /api/favorites/check?place_id=xxx
 func (h *FavoriteHandler) CheckFavorite(c *gin.Context) {
@@ -152,18 +386,22 @@ func (h *FavoriteHandler) ToggleFavorite(c *gin.Context) {
 		return
 	}
 
-	exists, err := h.repo.Exists(userID, req.PlaceID)
+	before, err := h.repo.Get(userID, req.PlaceID)
 	if err != nil {
 		InternalError(c, "즐겨찾기 확인 실패")
 		return
 	}
 
-	if exists {
+	if before != nil {
 		// Remove from favorites
-		if err := h.repo.Delete(userID, req.PlaceID); err != nil {
+		err := h.repo.Delete(userID, req.PlaceID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
 			InternalError(c, "즐겨찾기 삭제 실패")
 			return
 		}
+		if err == nil {
+			h.audit.Record(c, auditFavoriteDelete, "favorite:"+req.PlaceID, gin.H{"version": before.Version}, nil)
+		}
 		Success(c, gin.H{
 			"place_id":    req.PlaceID,
 			"is_favorite": false,
@@ -182,7 +420,8 @@ func (h *FavoriteHandler) ToggleFavorite(c *gin.Context) {
 			Phone:       req.Phone,
 			Category:    req.Category,
 		}
-		if err := h.repo.Add(favorite); err != nil {
+		// A concurrent toggle may have added it first, with the same result
+		if err := h.repo.Add(favorite); err != nil && !errors.Is(err, repository.ErrAlreadyExists) {
 			InternalError(c, "즐겨찾기 추가 실패")
 			return
 		}
@@ -193,3 +432,82 @@ func (h *FavoriteHandler) ToggleFavorite(c *gin.Context) {
 		})
 	}
 }
+
+// maxBulkOperations limits the size of a bulk favorites request
+const maxBulkOperations = 100
+
+// BulkFavorites applies several favorite operations in one transaction
+// POST /api/favorites/bulk
+// mode "atomic" (default) applies all or nothing; "best_effort" commits
+// every operation that succeeds.
+func (h *FavoriteHandler) BulkFavorites(c *gin.Context) {
+	userID := GetUserID(c)
+
+	var req struct {
+		Mode       string                     `json:"mode"`
+		Operations []models.FavoriteOperation `json:"operations" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "invalid request body")
+		return
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > maxBulkOperations {
+		BadRequest(c, "operations must contain 1 to 100 items")
+		return
+	}
+
+	var atomic bool
+	switch req.Mode {
+	case "", "atomic":
+		atomic = true
+	case "best_effort":
+		atomic = false
+	default:
+		BadRequest(c, "mode must be atomic or best_effort")
+		return
+	}
+
+	for i, op := range req.Operations {
+		if op.PlaceID == "" {
+			BadRequest(c, "operations["+strconv.Itoa(i)+"]: place_id is required")
+			return
+		}
+		switch op.Op {
+		case models.FavoriteOpAdd, models.FavoriteOpUpdate, models.FavoriteOpDelete:
+		default:
+			BadRequest(c, "operations["+strconv.Itoa(i)+"]: op must be add, update or delete")
+			return
+		}
+	}
+
+	results, committed, err := h.repo.Bulk(userID, req.Operations, atomic)
+	if err != nil {
+		InternalError(c, "즐겨찾기 일괄 처리 실패")
+		return
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.Status == repository.BulkStatusFailed {
+			failed++
+		}
+	}
+
+	data := gin.H{
+		"committed": committed,
+		"succeeded": len(results) - failed,
+		"failed":    failed,
+		"results":   results,
+	}
+	if !committed {
+		data["succeeded"] = 0
+		Conflict(c, "일괄 처리가 취소되었습니다", data)
+		return
+	}
+	h.audit.Record(c, auditFavoriteBulk, "favorites",
+		gin.H{"mode": req.Mode, "operations": len(req.Operations)},
+		gin.H{"succeeded": data["succeeded"], "failed": failed})
+
+	Success(c, data)
+}
@@ -1,23 +1,36 @@
 package handler
 
 import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/jju-compass/jju-compass-map/internal/models"
+	"github.com/jju-compass/jju-compass-map/internal/moderation"
 	"github.com/jju-compass/jju-compass-map/internal/repository"
 )
 
 // HistoryHandler handles search history requests
 type HistoryHandler struct {
-	repo *repository.HistoryRepository
+	repo       *repository.HistoryRepository
+	undoWindow time.Duration
+	rules      *moderation.Rules
+	userCap    int
+	audit      *Auditor
 }
 
-// NewHistoryHandler creates a new history handler
-func NewHistoryHandler(repo *repository.HistoryRepository) *HistoryHandler {
-	return &HistoryHandler{repo: repo}
+// NewHistoryHandler creates a new history handler. Popular and trending
+// keywords are public, so they are filtered by rules and each user's
+// contribution to a keyword is capped at userCap searches.
+func NewHistoryHandler(repo *repository.HistoryRepository, undoWindow time.Duration, rules *moderation.Rules, userCap int, audit *Auditor) *HistoryHandler {
+	return &HistoryHandler{repo: repo, undoWindow: undoWindow, rules: rules, userCap: userCap, audit: audit}
 }
 
-// GetHistory retrieves recent search history
-// GET /api/history?limit=20
+// GetHistory retrieves recent search history, optionally searched by
+// keyword and filtered by when it was last searched
+// GET /api/history?limit=20&q=카페&from=2024-03-01&to=2024-03-31
 func (h *HistoryHandler) GetHistory(c *gin.Context) {
 	userID := GetUserID(c)
 
@@ -28,7 +41,18 @@ func (h *HistoryHandler) GetHistory(c *gin.Context) {
 		}
 	}
 
-	history, err := h.repo.GetRecent(userID, limit)
+	from, to, err := parseTimeRange(c)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	history, err := h.repo.List(userID, repository.HistoryListOptions{
+		Query: strings.TrimSpace(c.Query("q")),
+		From:  from,
+		To:    to,
+		Limit: limit,
+	})
 	if err != nil {
 		InternalError(c, "검색 기록 조회 실패")
 		return
@@ -54,7 +78,11 @@ func (h *HistoryHandler) GetPopular(c *gin.Context) {
 		}
 	}
 
-	popular, err := h.repo.GetPopular(limit)
+	popular, err := h.repo.GetPopular(repository.PopularOptions{
+		Limit:   limit,
+		UserCap: h.userCap,
+		Exclude: h.rules.Blocked,
+	})
 	if err != nil {
 		InternalError(c, "인기 검색어 조회 실패")
 		return
@@ -70,19 +98,123 @@ func (h *HistoryHandler) GetPopular(c *gin.Context) {
 	})
 }
 
-// DeleteHistory clears search history for a user
-// DELETE /api/history
+// trendingWindows are the supported trending windows
+var trendingWindows = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+}
+
+// GetTrending retrieves keywords rising within a recent window
+// GET /api/history/trending?window=24h&limit=10
+func (h *HistoryHandler) GetTrending(c *gin.Context) {
+	windowName := c.DefaultQuery("window", "24h")
+	window, ok := trendingWindows[windowName]
+	if !ok {
+		BadRequest(c, "window must be 1h, 24h or 7d")
+		return
+	}
+
+	limit := 10
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := parseInt(l); err == nil && parsed > 0 && parsed <= 50 {
+			limit = parsed
+		}
+	}
+
+	trending, err := h.repo.GetTrending(window, limit, time.Now(), h.rules.Blocked)
+	if err != nil {
+		InternalError(c, "급상승 검색어 조회 실패")
+		return
+	}
+
+	if trending == nil {
+		trending = []models.TrendingKeyword{}
+	}
+
+	Success(c, gin.H{
+		"window":   windowName,
+		"keywords": trending,
+		"count":    len(trending),
+	})
+}
+
+// DeleteHistory clears search history for a user, or only the entry for
+// a keyword when one is given
+// DELETE /api/history?keyword=xxx
 func (h *HistoryHandler) DeleteHistory(c *gin.Context) {
 	userID := GetUserID(c)
 
+	if keyword, ok := c.GetQuery("keyword"); ok {
+		err := h.repo.DeleteKeyword(userID, keyword)
+		if errors.Is(err, repository.ErrNotFound) {
+			NotFound(c, "검색 기록을 찾을 수 없습니다")
+			return
+		}
+		if err != nil {
+			InternalError(c, "검색 기록 삭제 실패")
+			return
+		}
+		h.audit.Record(c, auditHistoryDelete, "history_keyword", nil, nil)
+		SuccessMessage(c, "검색 기록이 삭제되었습니다")
+		return
+	}
+
 	if err := h.repo.DeleteAll(userID); err != nil {
 		InternalError(c, "검색 기록 삭제 실패")
 		return
 	}
+	h.audit.Record(c, auditHistoryClear, "history", nil, nil)
 
 	SuccessMessage(c, "검색 기록이 삭제되었습니다")
 }
 
+// DeleteHistoryEntry removes a single history entry.
+// Entries of other users are reported as not found.
+// DELETE /api/history/:id
+func (h *HistoryHandler) DeleteHistoryEntry(c *gin.Context) {
+	userID := GetUserID(c)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		BadRequest(c, "invalid history id")
+		return
+	}
+
+	err = h.repo.Delete(userID, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		NotFound(c, "검색 기록을 찾을 수 없습니다")
+		return
+	}
+	if err != nil {
+		InternalError(c, "검색 기록 삭제 실패")
+		return
+	}
+	h.audit.Record(c, auditHistoryDelete, "history:"+c.Param("id"), nil, nil)
+
+	SuccessMessage(c, "검색 기록이 삭제되었습니다")
+}
+
+// RestoreHistory undoes the most recent history deletion
+// POST /api/history/restore
+func (h *HistoryHandler) RestoreHistory(c *gin.Context) {
+	userID := GetUserID(c)
+
+	restored, err := h.repo.Restore(userID, time.Now().Add(-h.undoWindow))
+	if err != nil {
+		InternalError(c, "검색 기록 복원 실패")
+		return
+	}
+	if restored == 0 {
+		NotFound(c, "복원할 검색 기록이 없습니다")
+		return
+	}
+
+	Success(c, gin.H{
+		"restored": restored,
+	})
+}
+
 // parseInt helper function
 func parseInt(s string) (int, error) {
 	var n int
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jju-compass/jju-compass-map/internal/repository"
+)
+
+// MeHandler handles personal data export and deletion requests
+type MeHandler struct {
+	data           *repository.PersonalDataRepository
+	auth           *AuthHandler
+	identityCookie string
+	audit          *Auditor
+}
+
+// NewMeHandler creates a new personal data handler
+func NewMeHandler(data *repository.PersonalDataRepository, auth *AuthHandler, identityCookie string, audit *Auditor) *MeHandler {
+	return &MeHandler{data: data, auth: auth, identityCookie: identityCookie, audit: audit}
+}
+
+// accountID returns the logged-in account's ID, or 0 for anonymous users
+func accountID(c *gin.Context) int64 {
+	if _, user := currentUser(c); user != nil {
+		return user.ID
+	}
+	return 0
+}
+
+// Export downloads everything stored about the user as a ZIP of JSON
+// files, one per table
+// GET /api/me/export
+func (h *MeHandler) Export(c *gin.Context) {
+	userID := GetUserID(c)
+	data, err := h.data.Export(userID, accountID(c))
+	if err != nil {
+		InternalError(c, "개인정보 내보내기 실패")
+		return
+	}
+
+	tables := make([]string, 0, len(data))
+	counts := make(map[string]int, len(data))
+	for table, rows := range data {
+		tables = append(tables, table)
+		counts[table] = len(rows)
+	}
+	sort.Strings(tables)
+
+	now := time.Now()
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="jju-compass-export-%s.zip"`, now.Format("20060102")))
+	c.Status(http.StatusOK)
+
+	zw := zip.NewWriter(c.Writer)
+	files := append([]string{"export"}, tables...)
+	for _, name := range files {
+		var content interface{}
+		if name == "export" {
+			content = gin.H{
+				"user_id":     userID,
+				"exported_at": now.UTC(),
+				"tables":      counts,
+			}
+		} else {
+			content = data[name]
+		}
+
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name + ".json", Method: zip.Deflate, Modified: now})
+		if err != nil {
+			log.Printf("Failed to write export for %s: %v", userID, err)
+			return
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(content); err != nil {
+			log.Printf("Failed to write export for %s: %v", userID, err)
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		log.Printf("Failed to write export for %s: %v", userID, err)
+	}
+}
+
+// DeleteMe queues the erasure of everything stored about the user and
+// signs the browser out. The returned job can be polled for its status.
+// DELETE /api/me
+func (h *MeHandler) DeleteMe(c *gin.Context) {
+	job, err := h.data.RequestDeletion(GetUserID(c), accountID(c))
+	if err != nil {
+		InternalError(c, "삭제 요청 실패")
+		return
+	}
+
+	h.audit.Record(c, auditAccountDeleteQueue, "deletion_job:"+job.ID, nil, job)
+	go h.run(job.ID)
+
+	// Later requests get a fresh identity with nothing attached to it
+	h.auth.clearSessionCookie(c)
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     h.identityCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		Secure:   h.auth.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	c.JSON(http.StatusAccepted, Response{
+		Success: true,
+		Data:    job,
+		Message: "삭제 요청이 접수되었습니다",
+	})
+}
+
+// run processes a deletion job right away. Jobs left pending, e.g. by a
+// restart, are picked up by the process-deletions background job.
+func (h *MeHandler) run(id string) {
+	if _, err := h.data.RunDeletion(id); err != nil {
+		log.Printf("Deletion job %s failed: %v", id, err)
+	}
+}
+
+// GetDeletion returns the status of a deletion job. The job ID is only
+// known to whoever requested it.
+// GET /api/me/deletions/:id
+func (h *MeHandler) GetDeletion(c *gin.Context) {
+	job, err := h.data.GetDeletion(c.Param("id"))
+	if err != nil {
+		InternalError(c, "삭제 요청 조회 실패")
+		return
+	}
+	if job == nil {
+		NotFound(c, "삭제 요청을 찾을 수 없습니다")
+		return
+	}
+
+	Success(c, job)
+}
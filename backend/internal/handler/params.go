@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// kst is the time zone used for date-only query parameters
+var kst = time.FixedZone("KST", 9*60*60)
+
+var errInvalidTimeRange = errors.New("from and to must be YYYY-MM-DD or RFC3339, with from before to")
+
+// parseTimeRange reads the optional "from" and "to" query parameters.
+// Dates without a time are taken as whole days in KST, so to=2024-03-31
+// includes all of March 31st.
+func parseTimeRange(c *gin.Context) (from, to time.Time, err error) {
+	if s := c.Query("from"); s != "" {
+		if from, err = parseTimeParam(s, false); err != nil {
+			return
+		}
+	}
+	if s := c.Query("to"); s != "" {
+		if to, err = parseTimeParam(s, true); err != nil {
+			return
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		err = errInvalidTimeRange
+	}
+	return
+}
+
+// parseTimeParam parses an RFC3339 time or a KST date.
+// With endOfDay a date yields the start of the following day.
+func parseTimeParam(s string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, kst)
+	if err != nil {
+		return time.Time{}, errInvalidTimeRange
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
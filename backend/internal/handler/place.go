@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jju-compass/jju-compass-map/internal/moderation"
+	"github.com/jju-compass/jju-compass-map/internal/repository"
+)
+
+// PlaceHandler handles the local place catalog
+type PlaceHandler struct {
+	repo  *repository.PlaceRepository
+	rules *moderation.Rules
+}
+
+// NewPlaceHandler creates a new place handler. The keywords a place
+// appeared for are public, so they are filtered by rules.
+func NewPlaceHandler(repo *repository.PlaceRepository, rules *moderation.Rules) *PlaceHandler {
+	return &PlaceHandler{repo: repo, rules: rules}
+}
+
+// SearchPlaces searches every place fetched so far without calling Kakao
+// GET /api/places/search?q=xxx&limit=20
+func (h *PlaceHandler) SearchPlaces(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		BadRequest(c, "q is required")
+		return
+	}
+
+	limit := 20
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := parseInt(l); err == nil && parsed > 0 && parsed <= 50 {
+			limit = parsed
+		}
+	}
+
+	places, err := h.repo.Search(q, limit)
+	if err != nil {
+		InternalError(c, "장소 검색 실패")
+		return
+	}
+
+	Success(c, gin.H{
+		"query":  q,
+		"places": places,
+		"count":  len(places),
+	})
+}
+
+// GetPlace returns a place from the local catalog with its favorite count
+// and the searches it recently appeared in
+// GET /api/places/:id
+func (h *PlaceHandler) GetPlace(c *gin.Context) {
+	userID := GetUserID(c)
+	placeID := c.Param("id")
+
+	place, err := h.repo.Get(placeID, userID, 10, h.rules.Blocked)
+	if err != nil {
+		InternalError(c, "장소 조회 실패")
+		return
+	}
+	if place == nil {
+		NotFound(c, "장소를 찾을 수 없습니다")
+		return
+	}
+
+	Success(c, place)
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jju-compass/jju-compass-map/internal/middleware"
 )
 
 // Response represents a standard API response
@@ -51,23 +52,36 @@ func BadRequest(c *gin.Context, message string) {
 	Error(c, http.StatusBadRequest, message)
 }
 
+// Unauthorized returns a 401 error
+func Unauthorized(c *gin.Context, message string) {
+	Error(c, http.StatusUnauthorized, message)
+}
+
+// Forbidden returns a 403 error
+func Forbidden(c *gin.Context, message string) {
+	Error(c, http.StatusForbidden, message)
+}
+
+// NotFound returns a 404 error
+func NotFound(c *gin.Context, message string) {
+	Error(c, http.StatusNotFound, message)
+}
+
+// Conflict returns a 409 error along with the current state of the resource
+func Conflict(c *gin.Context, message string, current interface{}) {
+	c.JSON(http.StatusConflict, Response{
+		Success: false,
+		Data:    current,
+		Error:   message,
+	})
+}
+
 // InternalError returns a 500 error
 func InternalError(c *gin.Context, message string) {
 	Error(c, http.StatusInternalServerError, message)
 }
 
-// GetUserID extracts user ID from request (cookie or header)
+// GetUserID returns the user ID verified by the identity middleware
 func GetUserID(c *gin.Context) string {
-	// Try to get from cookie first
-	if userID, err := c.Cookie("user_id"); err == nil && userID != "" {
-		return userID
-	}
-
-	// Try header
-	if userID := c.GetHeader("X-User-ID"); userID != "" {
-		return userID
-	}
-
-	// Default user for anonymous users
-	return "anonymous"
+	return middleware.UserID(c)
 }
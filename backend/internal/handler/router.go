@@ -5,9 +5,12 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jju-compass/jju-compass-map/internal/auth"
 	"github.com/jju-compass/jju-compass-map/internal/config"
 	"github.com/jju-compass/jju-compass-map/internal/middleware"
+	"github.com/jju-compass/jju-compass-map/internal/moderation"
 	"github.com/jju-compass/jju-compass-map/internal/repository"
+	"github.com/jju-compass/jju-compass-map/internal/suggest"
 )
 
 // Handlers holds all handler instances
@@ -16,21 +19,52 @@ type Handlers struct {
 	Favorite   *FavoriteHandler
 	History    *HistoryHandler
 	Directions *DirectionsHandler
+	Visit      *VisitHandler
+	SharedList *SharedListHandler
+	Suggest    *SuggestHandler
+	Place      *PlaceHandler
+	Admin      *AdminHandler
+	Auth       *AuthHandler
+	Me         *MeHandler
+	APIKey     *APIKeyHandler
+
+	// Limits holds the rate limit policies applied to route groups
+	Limits *middleware.RateLimiter
 }
 
 // NewHandlers creates all handlers with their dependencies
-func NewHandlers(db *sql.DB, cfg *config.Config, apiLimiter *middleware.DailyAPILimiter) *Handlers {
+func NewHandlers(db *sql.DB, cfg *config.Config, apiLimiter *middleware.DailyAPILimiter, limits *middleware.RateLimiter, suggestIndex *suggest.Index, rules *moderation.Rules) *Handlers {
 	historyRepo := repository.NewHistoryRepository(db)
-	return &Handlers{
-		Cache:      NewCacheHandler(repository.NewCacheRepository(db), historyRepo),
-		Favorite:   NewFavoriteHandler(repository.NewFavoriteRepository(db)),
-		History:    NewHistoryHandler(historyRepo),
+	visitRepo := repository.NewVisitRepository(db)
+	moderationRepo := repository.NewModerationRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	auditor := NewAuditor(repository.NewAuditRepository(db))
+	h := &Handlers{
+		Cache:      NewCacheHandler(repository.NewCacheRepository(db), historyRepo, auditor),
+		Favorite:   NewFavoriteHandler(repository.NewFavoriteRepository(db), repository.NewVerificationRepository(db), visitRepo, cfg.Deletion.GracePeriod, auditor),
+		History:    NewHistoryHandler(historyRepo, cfg.Deletion.GracePeriod, rules, cfg.Moderation.UserCap, auditor),
 		Directions: NewDirectionsHandler(&cfg.Kakao, apiLimiter),
+		Visit:      NewVisitHandler(visitRepo),
+		SharedList: NewSharedListHandler(repository.NewSharedListRepository(db), auditor),
+		Suggest:    NewSuggestHandler(suggestIndex, historyRepo),
+		Place:      NewPlaceHandler(repository.NewPlaceRepository(db), rules),
+		APIKey:     NewAPIKeyHandler(repository.NewAPIKeyRepository(db), limits, auditor),
+		Admin: NewAdminHandler(repository.NewReportRepository(db), moderationRepo, userRepo, suggestIndex,
+			map[string]*middleware.DailyAPILimiter{"directions": apiLimiter}, cfg.Admin.Tokens, auditor),
+		Limits: limits,
 	}
+	h.Auth = NewAuthHandler(userRepo, auth.NewKakaoClient(&cfg.Auth), &cfg.Auth, cfg.Identity.Secure)
+	h.Me = NewMeHandler(repository.NewPersonalDataRepository(db), h.Auth, cfg.Identity.CookieName, auditor)
+	return h
 }
 
-// RegisterRoutes registers all API routes
-func (h *Handlers) RegisterRoutes(router *gin.Engine) {
+// RegisterRoutes registers all API routes. identity, the session check and
+// API key authentication run before every API handler so that GetUserID
+// always has a verified user: the API key for third-party clients, the
+// account when logged in, the anonymous identity otherwise. A per-IP limit
+// runs ahead of them, since they cost database lookups before any other
+// limit applies.
+func (h *Handlers) RegisterRoutes(router *gin.Engine, identity gin.HandlerFunc) {
 	// Health check endpoints
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -48,37 +82,120 @@ func (h *Handlers) RegisterRoutes(router *gin.Engine) {
 	})
 
 	// API group
-	api := router.Group("/api")
+	api := router.Group("/api", h.Limits.Limit("ip"), identity, h.Auth.Session(), h.APIKey.Authenticate())
 	{
+		// Login, counted per IP since the user is not known yet
+		login := api.Group("/auth/kakao", h.Limits.Limit("auth"))
+		{
+			login.GET("/login", h.Auth.KakaoLogin)
+			login.GET("/callback", h.Auth.KakaoCallback)
+		}
+
+		// Cheap routes that clients call often, with a policy of their own
+		polling := api.Group("", h.Limits.Limit("polling"))
+		{
+			polling.GET("/favorites/check", h.Favorite.CheckFavorite)
+			polling.GET("/suggest", h.Suggest.GetSuggestions)
+			polling.GET("/directions/usage", h.Directions.GetAPIUsage)
+		}
+
+		// Directions spend the daily Kakao Directions quota
+		api.GET("/directions", h.Limits.Limit("directions"), h.Directions.GetDirections)
+
+		std := api.Group("", h.Limits.Limit("default"))
+
+		// Auth routes
+		authGroup := std.Group("/auth")
+		{
+			authGroup.GET("/me", h.Auth.GetMe)
+			authGroup.GET("/sessions", h.Auth.GetSessions)
+			authGroup.POST("/merge", h.Auth.MergeAnonymous)
+			authGroup.POST("/logout", h.Auth.Logout)
+			authGroup.POST("/logout-all", h.Auth.LogoutAll)
+		}
+
+		// Personal data routes
+		me := std.Group("/me")
+		{
+			me.GET("/export", h.Me.Export)
+			me.DELETE("", h.Me.DeleteMe)
+			me.GET("/deletions/:id", h.Me.GetDeletion)
+		}
+
 		// Cache routes
-		cache := api.Group("/cache")
+		cache := std.Group("/cache")
 		{
 			cache.GET("/search", h.Cache.GetSearchCache)
 			cache.POST("/search", h.Cache.SetSearchCache)
-			cache.GET("/stats", h.Cache.GetCacheStats)
-			cache.DELETE("", h.Cache.DeleteCache)
 		}
 
 		// Favorites routes
-		favorites := api.Group("/favorites")
+		favorites := std.Group("/favorites")
 		{
 			favorites.GET("", h.Favorite.GetFavorites)
 			favorites.POST("", h.Favorite.AddFavorite)
 			favorites.DELETE("", h.Favorite.DeleteFavorite)
-			favorites.GET("/check", h.Favorite.CheckFavorite)
+			favorites.PATCH("/:place_id", h.Favorite.UpdateFavorite)
+			favorites.POST("/restore", h.Favorite.RestoreFavorite)
+			favorites.POST("/bulk", h.Favorite.BulkFavorites)
 			favorites.POST("/check", h.Favorite.ToggleFavorite)
 		}
 
 		// History routes
-		history := api.Group("/history")
+		history := std.Group("/history")
 		{
 			history.GET("", h.History.GetHistory)
 			history.GET("/popular", h.History.GetPopular)
+			history.GET("/trending", h.History.GetTrending)
 			history.DELETE("", h.History.DeleteHistory)
+			history.DELETE("/:id", h.History.DeleteHistoryEntry)
+			history.POST("/restore", h.History.RestoreHistory)
 		}
 
-		// Directions routes
-		api.GET("/directions", h.Directions.GetDirections)
-		api.GET("/directions/usage", h.Directions.GetAPIUsage)
+		// Visit routes
+		visits := std.Group("/visits")
+		{
+			visits.GET("", h.Visit.GetVisits)
+			visits.POST("", h.Visit.AddVisit)
+			visits.GET("/stats", h.Visit.GetVisitStats)
+		}
+
+		// Shared list routes
+		lists := std.Group("/lists")
+		{
+			lists.GET("", h.SharedList.GetMyLists)
+			lists.POST("", h.SharedList.CreateList)
+			lists.GET("/:slug", h.SharedList.GetPublicList)
+			lists.DELETE("/:slug", h.SharedList.RevokeList)
+			lists.POST("/:slug/copy", h.SharedList.CopyList)
+			lists.POST("/:slug/rotate", h.SharedList.RotateList)
+		}
+
+		// Place corpus routes
+		places := std.Group("/places")
+		{
+			places.GET("/search", h.Place.SearchPlaces)
+			places.GET("/:id", h.Place.GetPlace)
+		}
+
+		// Admin routes, for admin bearer tokens and admin accounts only
+		admin := std.Group("/admin", h.Admin.RequireAdmin())
+		{
+			admin.GET("/reports/low-results", h.Admin.GetLowResultReport)
+			admin.GET("/keywords", h.Admin.GetModeratedKeywords)
+			admin.PUT("/keywords", h.Admin.ModerateKeyword)
+			admin.DELETE("/keywords", h.Admin.UnmoderateKeyword)
+			admin.GET("/cache/stats", h.Cache.GetCacheStats)
+			admin.DELETE("/cache", h.Cache.DeleteCache)
+			admin.GET("/quotas", h.Admin.GetQuotas)
+			admin.POST("/quotas/:name/reset", h.Admin.ResetQuota)
+			admin.GET("/users", h.Admin.FindUsers)
+			admin.GET("/users/:id", h.Admin.GetUser)
+			admin.PUT("/users/:id/admin", h.Admin.SetUserAdmin)
+			admin.GET("/api-keys", h.APIKey.GetAPIKeys)
+			admin.POST("/api-keys", h.APIKey.IssueAPIKey)
+			admin.DELETE("/api-keys/:id", h.APIKey.RevokeAPIKey)
+			admin.GET("/audit", h.Admin.GetAuditLog)
+		}
 	}
 }
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jju-compass/jju-compass-map/internal/models"
+	"github.com/jju-compass/jju-compass-map/internal/repository"
+)
+
+// SharedListHandler handles shareable favorite list requests
+type SharedListHandler struct {
+	repo  *repository.SharedListRepository
+	audit *Auditor
+}
+
+// NewSharedListHandler creates a new shared list handler
+func NewSharedListHandler(repo *repository.SharedListRepository, audit *Auditor) *SharedListHandler {
+	return &SharedListHandler{repo: repo, audit: audit}
+}
+
+// CreateList publishes the user's favorites, or a subset of them
+// POST /api/lists
+func (h *SharedListHandler) CreateList(c *gin.Context) {
+	userID := GetUserID(c)
+
+	var req struct {
+		Title       string   `json:"title" binding:"required"`
+		Description string   `json:"description"`
+		PlaceIDs    []string `json:"place_ids"` // empty = all favorites
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "invalid request body")
+		return
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" || len([]rune(req.Title)) > 100 {
+		BadRequest(c, "title must be 1 to 100 characters")
+		return
+	}
+	if len(req.PlaceIDs) > 500 {
+		BadRequest(c, "too many place_ids")
+		return
+	}
+
+	list, err := h.repo.Create(userID, req.Title, req.Description, req.PlaceIDs)
+	if errors.Is(err, repository.ErrNotFound) {
+		BadRequest(c, "즐겨찾기에 없는 장소입니다")
+		return
+	}
+	if err != nil {
+		InternalError(c, "목록 공유 실패")
+		return
+	}
+
+	Created(c, list)
+}
+
+// GetMyLists retrieves the lists published by the user
+// GET /api/lists
+func (h *SharedListHandler) GetMyLists(c *gin.Context) {
+	userID := GetUserID(c)
+
+	lists, err := h.repo.GetOwned(userID)
+	if err != nil {
+		InternalError(c, "공유 목록 조회 실패")
+		return
+	}
+
+	if lists == nil {
+		lists = []models.SharedList{}
+	}
+
+	Success(c, gin.H{
+		"lists": lists,
+		"count": len(lists),
+	})
+}
+
+// GetPublicList retrieves a shared list by its slug
+// GET /api/lists/:slug
+func (h *SharedListHandler) GetPublicList(c *gin.Context) {
+	list, err := h.repo.GetPublic(c.Param("slug"))
+	if err != nil {
+		InternalError(c, "공유 목록 조회 실패")
+		return
+	}
+	if list == nil {
+		NotFound(c, "공유 목록을 찾을 수 없습니다")
+		return
+	}
+
+	Success(c, list)
+}
+
+// CopyList adds every place of a shared list to the user's favorites
+// POST /api/lists/:slug/copy
+func (h *SharedListHandler) CopyList(c *gin.Context) {
+	userID := GetUserID(c)
+
+	added, skipped, err := h.repo.CopyToFavorites(c.Param("slug"), userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		NotFound(c, "공유 목록을 찾을 수 없습니다")
+		return
+	}
+	if err != nil {
+		InternalError(c, "즐겨찾기 복사 실패")
+		return
+	}
+
+	Success(c, gin.H{
+		"added":   added,
+		"skipped": skipped,
+	})
+}
+
+// RotateList replaces the slug of the user's list, invalidating the old link
+// POST /api/lists/:slug/rotate
+func (h *SharedListHandler) RotateList(c *gin.Context) {
+	userID := GetUserID(c)
+
+	list, err := h.repo.Rotate(userID, c.Param("slug"))
+	if errors.Is(err, repository.ErrNotFound) {
+		NotFound(c, "공유 목록을 찾을 수 없습니다")
+		return
+	}
+	if err != nil {
+		InternalError(c, "공유 링크 변경 실패")
+		return
+	}
+
+	Success(c, list)
+}
+
+// RevokeList unpublishes the user's list
+// DELETE /api/lists/:slug
+func (h *SharedListHandler) RevokeList(c *gin.Context) {
+	userID := GetUserID(c)
+
+	err := h.repo.Revoke(userID, c.Param("slug"))
+	if errors.Is(err, repository.ErrNotFound) {
+		NotFound(c, "공유 목록을 찾을 수 없습니다")
+		return
+	}
+	if err != nil {
+		InternalError(c, "공유 해제 실패")
+		return
+	}
+	h.audit.Record(c, auditListRevoke, "list:"+c.Param("slug"), nil, nil)
+
+	SuccessMessage(c, "공유가 해제되었습니다")
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jju-compass/jju-compass-map/internal/repository"
+	"github.com/jju-compass/jju-compass-map/internal/suggest"
+	"github.com/jju-compass/jju-compass-map/internal/textnorm"
+)
+
+// historySuggestionWeight ranks the user's own history above global completions
+const historySuggestionWeight = 100.0
+
+// SuggestHandler handles search-as-you-type requests
+type SuggestHandler struct {
+	index       *suggest.Index
+	historyRepo *repository.HistoryRepository
+}
+
+// NewSuggestHandler creates a new suggest handler
+func NewSuggestHandler(index *suggest.Index, historyRepo *repository.HistoryRepository) *SuggestHandler {
+	return &SuggestHandler{index: index, historyRepo: historyRepo}
+}
+
+// GetSuggestions returns completions for a partially typed keyword,
+// blending the user's own history with global completions
+// GET /api/suggest?q=xxx&limit=10
+func (h *SuggestHandler) GetSuggestions(c *gin.Context) {
+	userID := GetUserID(c)
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		BadRequest(c, "q is required")
+		return
+	}
+
+	limit := 10
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := parseInt(l); err == nil && parsed > 0 && parsed <= 20 {
+			limit = parsed
+		}
+	}
+
+	history, err := h.historyRepo.SearchPrefix(userID, q, limit)
+	if err != nil {
+		InternalError(c, "검색어 추천 실패")
+		return
+	}
+
+	suggestions := make([]suggest.Suggestion, 0, limit)
+	seen := make(map[string]bool)
+	for i, entry := range history {
+		seen[textnorm.Key(entry.Keyword)] = true
+		suggestions = append(suggestions, suggest.Suggestion{
+			Text:  entry.Keyword,
+			Kind:  suggest.KindHistory,
+			Score: historySuggestionWeight - float64(i),
+		})
+	}
+
+	for _, s := range h.index.Search(q, limit*2) {
+		if len(suggestions) >= limit {
+			break
+		}
+		key := textnorm.Key(s.Text)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		suggestions = append(suggestions, s)
+	}
+
+	Success(c, gin.H{
+		"query":       q,
+		"suggestions": suggestions,
+		"count":       len(suggestions),
+	})
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jju-compass/jju-compass-map/internal/models"
+	"github.com/jju-compass/jju-compass-map/internal/repository"
+)
+
+// VisitHandler handles visit log requests
+type VisitHandler struct {
+	repo *repository.VisitRepository
+}
+
+// NewVisitHandler creates a new visit handler
+func NewVisitHandler(repo *repository.VisitRepository) *VisitHandler {
+	return &VisitHandler{repo: repo}
+}
+
+// AddVisit logs a visit to a place
+// POST /api/visits
+func (h *VisitHandler) AddVisit(c *gin.Context) {
+	userID := GetUserID(c)
+
+	var req struct {
+		PlaceID   string     `json:"place_id" binding:"required"`
+		PlaceName string     `json:"place_name" binding:"required"`
+		Category  string     `json:"category"`
+		Lat       float64    `json:"lat"`
+		Lng       float64    `json:"lng"`
+		VisitedAt *time.Time `json:"visited_at"` // defaults to now
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BadRequest(c, "invalid request body")
+		return
+	}
+
+	visitedAt := time.Now()
+	if req.VisitedAt != nil {
+		if req.VisitedAt.After(visitedAt.Add(time.Minute)) {
+			BadRequest(c, "visited_at cannot be in the future")
+			return
+		}
+		visitedAt = *req.VisitedAt
+	}
+
+	visit := &models.Visit{
+		UserID:    userID,
+		PlaceID:   req.PlaceID,
+		PlaceName: strings.TrimSpace(req.PlaceName),
+		Category:  req.Category,
+		Lat:       req.Lat,
+		Lng:       req.Lng,
+		VisitedAt: visitedAt.UTC().Truncate(time.Second),
+	}
+
+	if err := h.repo.Add(visit); err != nil {
+		InternalError(c, "방문 기록 추가 실패")
+		return
+	}
+
+	Created(c, visit)
+}
+
+// GetVisits retrieves the user's visits
+// GET /api/visits?from=2024-03-01&to=2024-03-31&limit=50
+func (h *VisitHandler) GetVisits(c *gin.Context) {
+	userID := GetUserID(c)
+
+	from, to, err := parseTimeRange(c)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	limit := 50
+	if l := c.Query("limit"); l != "" {
+		if parsed, err := parseInt(l); err == nil && parsed > 0 && parsed <= 500 {
+			limit = parsed
+		}
+	}
+
+	visits, err := h.repo.List(userID, from, to, limit)
+	if err != nil {
+		InternalError(c, "방문 기록 조회 실패")
+		return
+	}
+
+	if visits == nil {
+		visits = []models.Visit{}
+	}
+
+	Success(c, gin.H{
+		"visits": visits,
+		"count":  len(visits),
+	})
+}
+
+// GetVisitStats returns monthly visit totals by category and the most visited places
+// GET /api/visits/stats?from=2024-03-01&to=2024-06-30&top=5
+func (h *VisitHandler) GetVisitStats(c *gin.Context) {
+	userID := GetUserID(c)
+
+	from, to, err := parseTimeRange(c)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	top := 5
+	if t := c.Query("top"); t != "" {
+		if parsed, err := parseInt(t); err == nil && parsed > 0 && parsed <= 50 {
+			top = parsed
+		}
+	}
+
+	stats, err := h.repo.Stats(userID, from, to, top)
+	if err != nil {
+		InternalError(c, "방문 통계 조회 실패")
+		return
+	}
+
+	Success(c, stats)
+}
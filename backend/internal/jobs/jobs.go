@@ -0,0 +1,46 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Every runs fn once per interval until ctx is cancelled.
+// Errors are logged and do not stop the schedule.
+func Every(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context) error) {
+	if interval <= 0 {
+		log.Printf("Job %s not scheduled: interval must be positive, got %v", name, interval)
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			run(ctx, name, fn)
+		}
+	}
+}
+
+// EveryFromStart runs fn right away and then like Every, for jobs with
+// intervals long enough that a server restarted more often would never
+// run them
+func EveryFromStart(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context) error) {
+	if interval <= 0 {
+		log.Printf("Job %s not scheduled: interval must be positive, got %v", name, interval)
+		return
+	}
+	run(ctx, name, fn)
+	Every(ctx, name, interval, fn)
+}
+
+// run runs fn once, logging its error
+func run(ctx context.Context, name string, fn func(ctx context.Context) error) {
+	if err := fn(ctx); err != nil {
+		log.Printf("Job %s failed: %v", name, err)
+	}
+}
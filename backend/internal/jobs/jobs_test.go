@@ -0,0 +1,41 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestEveryFromStartRunsRightAway(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := make(chan struct{}, 1)
+	done := make(chan struct{})
+	go func() {
+		EveryFromStart(ctx, "test", time.Hour, func(context.Context) error {
+			ran <- struct{}{}
+			return nil
+		})
+		close(done)
+	}()
+
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("job did not run at start")
+	}
+	cancel()
+	<-done
+}
+
+func TestEveryRejectsNonPositiveIntervals(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Minute} {
+		ran := false
+		fn := func(context.Context) error { ran = true; return nil }
+		// Both return at once instead of panicking in time.NewTicker
+		Every(context.Background(), "test", interval, fn)
+		EveryFromStart(context.Background(), "test", interval, fn)
+		if ran {
+			t.Errorf("interval %v: job ran", interval)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jju-compass/jju-compass-map/internal/repository"
+)
+
+// PurgeDeleted returns a job that hard-deletes favorites and history
+// whose soft-delete grace period has passed
+func PurgeDeleted(favorites *repository.FavoriteRepository, history *repository.HistoryRepository, grace time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		before := time.Now().Add(-grace)
+
+		favCount, err := favorites.PurgeDeleted(before)
+		if err != nil {
+			return err
+		}
+		histCount, err := history.PurgeDeleted(before)
+		if err != nil {
+			return err
+		}
+
+		if favCount > 0 || histCount > 0 {
+			log.Printf("Purged %d favorites and %d history entries", favCount, histCount)
+		}
+		return nil
+	}
+}
+
+// PruneTrends returns a job that drops trend buckets no trending window can reach
+func PruneTrends(history *repository.HistoryRepository) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := history.PruneTrends(time.Now().Add(-repository.TrendRetention))
+		return err
+	}
+}
+
+// PurgeSessions returns a job that removes sessions that have expired or
+// were revoked longer ago than retention
+func PurgeSessions(users *repository.UserRepository, retention time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		count, err := users.PurgeSessions(time.Now().Add(-retention))
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			log.Printf("Purged %d sessions", count)
+		}
+		return nil
+	}
+}
+
+// ProcessDeletions returns a job that runs personal data deletion jobs
+// still pending, such as those interrupted by a restart
+func ProcessDeletions(data *repository.PersonalDataRepository) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		ids, err := data.PendingDeletions()
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if _, err := data.RunDeletion(id); err != nil {
+				log.Printf("Deletion job %s failed: %v", id, err)
+			}
+		}
+		return nil
+	}
+}
+
+// PurgeAudit returns a job that removes audit entries older than
+// retention. The repository never purges within AuditMinRetention.
+func PurgeAudit(audit *repository.AuditRepository, retention time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		count, err := audit.Purge(time.Now().Add(-retention))
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			log.Printf("Purged %d audit entries", count)
+		}
+		return nil
+	}
+}
+
+// RollupSearchEvents returns a job that folds search events older than
+// retention into the per-keyword totals behind popular keywords
+func RollupSearchEvents(history *repository.HistoryRepository, retention time.Duration, userCap int) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		count, err := history.RollupEvents(time.Now().Add(-retention), userCap)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			log.Printf("Rolled up %d search events", count)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,54 @@
+package jobs
+
+import (
+	"context"
+
+	"github.com/jju-compass/jju-compass-map/internal/models"
+	"github.com/jju-compass/jju-compass-map/internal/repository"
+	"github.com/jju-compass/jju-compass-map/internal/suggest"
+)
+
+// suggestPopularLimit bounds how many popular keywords are indexed
+const suggestPopularLimit = 5000
+
+// RebuildSuggestions returns a job that rebuilds the autocomplete index
+// from popular keywords, cached places and category names. It is the only
+// way into the index: popular keywords are moderated the same way as on
+// the popular list, and cached places, which clients upload, are left
+// out when popularOpts.Exclude rejects their name or category.
+func RebuildSuggestions(index *suggest.Index, history *repository.HistoryRepository, cache *repository.CacheRepository, popularOpts repository.PopularOptions) func(ctx context.Context) error {
+	popularOpts.Limit = suggestPopularLimit
+	return func(ctx context.Context) error {
+		fresh := suggest.NewIndex()
+		fresh.AddCategories()
+
+		popular, err := history.GetPopular(popularOpts)
+		if err != nil {
+			return err
+		}
+		fresh.AddPopular(popular)
+
+		places, err := cache.GetCachedPlaces()
+		if err != nil {
+			return err
+		}
+		fresh.AddPlaces(allowedPlaces(places, popularOpts.Exclude))
+
+		index.Replace(fresh)
+		return nil
+	}
+}
+
+// allowedPlaces returns the places whose name and category pass exclude
+func allowedPlaces(places []models.Place, exclude func(string) bool) []models.Place {
+	if exclude == nil {
+		return places
+	}
+	allowed := make([]models.Place, 0, len(places))
+	for _, p := range places {
+		if !exclude(p.PlaceName) && !exclude(p.CategoryName) {
+			allowed = append(allowed, p)
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,66 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jju-compass/jju-compass-map/internal/models"
+	"github.com/jju-compass/jju-compass-map/internal/placesource"
+	"github.com/jju-compass/jju-compass-map/internal/repository"
+)
+
+// VerifyFavorites returns a job that re-checks favorited places against
+// the place source. Each run checks at most batch places not verified
+// within recheckAfter, and stops early when the source's daily budget is
+// spent.
+func VerifyFavorites(repo *repository.VerificationRepository, source placesource.Source, batch int, recheckAfter time.Duration) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		due, err := repo.Due(time.Now().Add(-recheckAfter), batch)
+		if err != nil {
+			return err
+		}
+
+		checked := 0
+		for _, f := range due {
+			if ctx.Err() != nil {
+				break
+			}
+			place, err := source.Lookup(ctx, placesource.Ref{
+				PlaceID:     f.PlaceID,
+				PlaceName:   f.PlaceName,
+				Address:     f.Address,
+				RoadAddress: f.RoadAddress,
+				Lat:         f.Lat,
+				Lng:         f.Lng,
+			})
+			if errors.Is(err, placesource.ErrBudgetExhausted) {
+				log.Println("Place verification budget exhausted for today")
+				break
+			}
+			if errors.Is(err, placesource.ErrUnavailable) {
+				continue
+			}
+
+			var v models.PlaceVerification
+			switch {
+			case errors.Is(err, placesource.ErrUnknown):
+				v = placesource.UnknownVerification(f.PlaceID)
+			case err != nil:
+				return err
+			default:
+				v = placesource.ToVerification(f.PlaceID, place)
+			}
+			if err := repo.Save(&v); err != nil {
+				return err
+			}
+			checked++
+		}
+
+		if checked > 0 {
+			log.Printf("Verified %d favorited places", checked)
+		}
+		return nil
+	}
+}
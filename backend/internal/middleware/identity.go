@@ -0,0 +1,278 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jju-compass/jju-compass-map/internal/config"
+)
+
+// UserIDKey is the context key holding the verified user ID
+const UserIDKey = "user_id"
+
+// AnonymousIDKey is the context key holding the cookie identity, which
+// stays available after a login replaces the user ID
+const AnonymousIDKey = "anonymous_id"
+
+// anonymousIDPrefix marks IDs issued to visitors without an account
+const anonymousIDPrefix = "anon_"
+
+// minSecretLength is the shortest accepted signing secret
+const minSecretLength = 16
+
+// reservedIDPrefixes start the IDs the server issues itself. A legacy
+// cookie claiming one of them would take over someone else's data.
+var reservedIDPrefixes = []string{anonymousIDPrefix, "user_", "key_", "erased_"}
+
+// LegacyClaim records that a legacy user ID has been adopted, reporting
+// false if it was adopted before
+type LegacyClaim func(userID string) (bool, error)
+
+// signingKey is one of the identity cookie signing keys
+type signingKey struct {
+	id     string
+	secret []byte
+	until  time.Time // retired keys verify until then; zero = no limit
+}
+
+// Identity issues and verifies the signed anonymous identity cookie.
+// The cookie value is "<user id>.<key id>.<signature>", where the
+// signature is an HMAC-SHA256 of the user and key IDs. New cookies are
+// signed with the first key; the others are accepted during rotation and
+// cookies signed with them are re-signed with the active key.
+type Identity struct {
+	keys   []signingKey
+	cookie string
+	secure bool
+	maxAge time.Duration
+	now    func() time.Time
+
+	legacyCookie string
+	claimLegacy  LegacyClaim
+}
+
+// NewIdentity creates the identity middleware from configuration.
+// Keys are "kid:secret" with an optional ":YYYY-MM-DD" end of the grace
+// window for retired keys. Without keys a random one is generated, so
+// identities only last until the server restarts.
+func NewIdentity(cfg *config.IdentityConfig) (*Identity, error) {
+	id := &Identity{
+		cookie:       cfg.CookieName,
+		secure:       cfg.Secure,
+		maxAge:       cfg.MaxAge,
+		now:          time.Now,
+		legacyCookie: cfg.LegacyCookie,
+	}
+
+	seen := make(map[string]bool)
+	for _, raw := range cfg.Keys {
+		parts := strings.SplitN(raw, ":", 3)
+		if len(parts) < 2 || parts[0] == "" || strings.Contains(parts[0], ".") {
+			return nil, errors.New("identity key must be kid:secret[:YYYY-MM-DD]")
+		}
+		if seen[parts[0]] {
+			return nil, fmt.Errorf("duplicate identity key id %q", parts[0])
+		}
+		seen[parts[0]] = true
+		if len(parts[1]) < minSecretLength {
+			return nil, fmt.Errorf("identity key %q: secret must be at least %d characters", parts[0], minSecretLength)
+		}
+		key := signingKey{id: parts[0], secret: []byte(parts[1])}
+		if len(parts) == 3 {
+			until, err := time.Parse("2006-01-02", parts[2])
+			if err != nil {
+				return nil, fmt.Errorf("identity key %q: invalid grace date: %w", parts[0], err)
+			}
+			key.until = until.AddDate(0, 0, 1)
+		}
+		id.keys = append(id.keys, key)
+	}
+
+	if len(id.keys) == 0 {
+		log.Println("IDENTITY_KEYS not set: using a temporary signing key, identities reset on restart")
+		secret, err := randomToken(32)
+		if err != nil {
+			return nil, err
+		}
+		id.keys = []signingKey{{id: "tmp", secret: []byte(secret)}}
+	}
+	return id, nil
+}
+
+// AcceptLegacy lets visitors who still carry the unsigned user ID cookie
+// of the old identity scheme keep their favorites and history. Anyone
+// could present any unsigned ID, so this is meant for a short migration
+// window only: each legacy ID that owns data is adopted once, by the first
+// browser to present it, and re-issued as a signed identity. claim
+// records the adoption.
+func (id *Identity) AcceptLegacy(claim LegacyClaim) {
+	id.claimLegacy = claim
+}
+
+// Middleware verifies the identity cookie, issuing a new identity when it
+// is missing, unsigned or tampered with, and stores the user ID in the
+// context under UserIDKey
+func (id *Identity) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, keyID, ok := "", "", false
+		if value, err := c.Cookie(id.cookie); err == nil {
+			userID, keyID, ok = id.verify(value)
+		}
+
+		if !ok {
+			userID, ok = id.adoptLegacy(c)
+			keyID = ""
+		}
+		if !ok {
+			token, err := randomToken(16)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+					"success": false,
+					"error":   "사용자 식별자 발급 실패",
+				})
+				return
+			}
+			userID = anonymousIDPrefix + token
+		}
+		if !ok || keyID != id.keys[0].id {
+			id.setCookie(c, userID)
+		}
+
+		c.Set(UserIDKey, userID)
+		c.Set(AnonymousIDKey, userID)
+		c.Next()
+	}
+}
+
+// adoptLegacy returns the user ID of a legacy cookie that has not been
+// adopted yet, and removes the cookie
+func (id *Identity) adoptLegacy(c *gin.Context) (string, bool) {
+	if id.claimLegacy == nil || id.legacyCookie == "" {
+		return "", false
+	}
+	userID, err := c.Cookie(id.legacyCookie)
+	if err != nil {
+		return "", false
+	}
+	http.SetCookie(c.Writer, &http.Cookie{Name: id.legacyCookie, Path: "/", MaxAge: -1})
+	if !validLegacyID(userID) {
+		return "", false
+	}
+
+	claimed, err := id.claimLegacy(userID)
+	if err != nil {
+		log.Printf("Failed to adopt legacy identity: %v", err)
+		return "", false
+	}
+	return userID, claimed
+}
+
+// validLegacyID reports whether a legacy cookie value can be adopted: a
+// plain ID that is not the shared default and not one the server issues
+func validLegacyID(userID string) bool {
+	if userID == "" || len(userID) > 64 || userID == "anonymous" {
+		return false
+	}
+	for _, prefix := range reservedIDPrefixes {
+		if strings.HasPrefix(userID, prefix) {
+			return false
+		}
+	}
+	for _, r := range userID {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// UserID returns the user ID stored by the identity middleware
+func UserID(c *gin.Context) string {
+	return c.GetString(UserIDKey)
+}
+
+// AnonymousID returns the cookie identity of the request, even when the
+// user is logged in
+func AnonymousID(c *gin.Context) string {
+	return c.GetString(AnonymousIDKey)
+}
+
+// IsAnonymous reports whether userID was issued to a visitor without an account
+func IsAnonymous(userID string) bool {
+	return strings.HasPrefix(userID, anonymousIDPrefix)
+}
+
+// SetUserID replaces the user ID for the rest of the request, for
+// middleware that authenticates the user some other way
+func SetUserID(c *gin.Context, userID string) {
+	c.Set(UserIDKey, userID)
+}
+
+// sign returns the cookie value for userID signed with the active key
+func (id *Identity) sign(userID string) string {
+	key := id.keys[0]
+	return userID + "." + key.id + "." + signature(key.secret, userID, key.id)
+}
+
+// verify checks a cookie value and returns its user and key IDs
+func (id *Identity) verify(value string) (userID, keyID string, ok bool) {
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 || parts[0] == "" {
+		return "", "", false
+	}
+	userID, keyID = parts[0], parts[1]
+
+	now := id.now()
+	for _, key := range id.keys {
+		if key.id != keyID {
+			continue
+		}
+		if !key.until.IsZero() && !now.Before(key.until) {
+			return "", "", false
+		}
+		expected := signature(key.secret, userID, keyID)
+		if !hmac.Equal([]byte(parts[2]), []byte(expected)) {
+			return "", "", false
+		}
+		return userID, keyID, true
+	}
+	return "", "", false
+}
+
+// setCookie writes the signed identity cookie
+func (id *Identity) setCookie(c *gin.Context, userID string) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     id.cookie,
+		Value:    id.sign(userID),
+		Path:     "/",
+		MaxAge:   int(id.maxAge / time.Second),
+		Secure:   id.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// signature computes the HMAC of a user ID under a key
+func signature(secret []byte, userID, keyID string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(userID + "." + keyID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// randomToken returns n random bytes encoded as URL-safe base64
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
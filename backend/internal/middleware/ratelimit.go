@@ -1,63 +1,156 @@
 package middleware
 
 import (
+	"fmt"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// RateLimiter stores request counts per IP
-type RateLimiter struct {
-	requests map[string]*requestInfo
-	mu       sync.RWMutex
-	limit    int           // max requests per window
-	window   time.Duration // time window
+// RateLimitKey picks the bucket a request is counted in
+type RateLimitKey func(c *gin.Context) string
+
+// ipv6PrefixLen is the IPv6 prefix counted as one client. Networks
+// usually hand a whole /64 to a single host or home.
+const ipv6PrefixLen = 64
+
+// TrustProxies makes the router read the client IP from X-Forwarded-For
+// or X-Real-IP only when the peer is one of proxies (IPs or CIDRs). Anyone
+// else could claim any IP there and dodge the rate limits.
+func TrustProxies(router *gin.Engine, proxies []string) error {
+	router.RemoteIPHeaders = []string{"X-Forwarded-For", "X-Real-IP"}
+	return router.SetTrustedProxies(proxies)
 }
 
-type requestInfo struct {
-	count     int
-	startTime time.Time
+// KeyByIP counts requests per client IP, and per /64 for IPv6
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + clientNetwork(c.ClientIP())
 }
 
-// NewRateLimiter creates a new rate limiter
-func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
-	rl := &RateLimiter{
-		requests: make(map[string]*requestInfo),
-		limit:    limit,
-		window:   window,
+// clientNetwork returns an IPv4 address as is and the /64 network of an
+// IPv6 address
+func clientNetwork(addr string) string {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return addr
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.String()
 	}
+	network := net.IPNet{IP: ip.Mask(net.CIDRMask(ipv6PrefixLen, 128)), Mask: net.CIDRMask(ipv6PrefixLen, 128)}
+	return network.String()
+}
 
-	// Start cleanup goroutine
-	go rl.cleanup()
+// KeyByIdentity counts requests per account or API key. Anonymous
+// identities are free to mint, so those requests are counted per IP.
+// It needs the identity, session and API key middleware to run first.
+func KeyByIdentity(c *gin.Context) string {
+	if id := UserID(c); id != "" && !IsAnonymous(id) {
+		return "id:" + id
+	}
+	return KeyByIP(c)
+}
 
-	return rl
+// RateLimitPolicy is a named token bucket: Limit requests per Period on
+// average, with at most Burst of them back to back
+type RateLimitPolicy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+	Burst  int
+	Key    RateLimitKey
 }
 
-// RateLimit returns a Gin middleware that limits requests per IP
-func (rl *RateLimiter) RateLimit() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		ip := c.ClientIP()
+// ParseRateLimitPolicy reads a "limit/period/burst" spec such as
+// "100/1m/30". Burst may be left out and defaults to the limit.
+func ParseRateLimitPolicy(name, spec string, key RateLimitKey) (RateLimitPolicy, error) {
+	p := RateLimitPolicy{Name: name, Key: key}
+	parts := strings.Split(spec, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return p, fmt.Errorf("rate limit %s: %q is not limit/period/burst", name, spec)
+	}
+	var err error
+	if p.Limit, err = strconv.Atoi(parts[0]); err != nil || p.Limit <= 0 {
+		return p, fmt.Errorf("rate limit %s: invalid limit %q", name, parts[0])
+	}
+	if p.Period, err = time.ParseDuration(parts[1]); err != nil || p.Period <= 0 {
+		return p, fmt.Errorf("rate limit %s: invalid period %q", name, parts[1])
+	}
+	p.Burst = p.Limit
+	if len(parts) == 3 {
+		if p.Burst, err = strconv.Atoi(parts[2]); err != nil || p.Burst <= 0 {
+			return p, fmt.Errorf("rate limit %s: invalid burst %q", name, parts[2])
+		}
+	}
+	return p, nil
+}
+
+// rateLimitedKey marks a request whose rate was already limited
+const rateLimitedKey = "rate_limited"
+
+// MarkRateLimited exempts the request from route policies, for middleware
+// that limited it under a policy of its own
+func MarkRateLimited(c *gin.Context) {
+	c.Set(rateLimitedKey, true)
+}
 
-		rl.mu.Lock()
+// bucket holds the tokens left for one key under one policy
+type bucket struct {
+	tokens   float64
+	burst    float64
+	perToken time.Duration
+	updated  time.Time
+}
 
-		info, exists := rl.requests[ip]
-		now := time.Now()
+// RateLimiter applies token bucket policies to route groups
+type RateLimiter struct {
+	policies map[string]RateLimitPolicy
+	now      func() time.Time
 
-		if !exists || now.Sub(info.startTime) > rl.window {
-			// New window
-			rl.requests[ip] = &requestInfo{
-				count:     1,
-				startTime: now,
-			}
-			rl.mu.Unlock()
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewRateLimiter creates a rate limiter with the given policies. now is
+// the clock, nil for time.Now.
+func NewRateLimiter(now func() time.Time, policies ...RateLimitPolicy) *RateLimiter {
+	if now == nil {
+		now = time.Now
+	}
+	rl := &RateLimiter{
+		policies:  make(map[string]RateLimitPolicy, len(policies)),
+		now:       now,
+		buckets:   make(map[string]*bucket),
+		lastSweep: now(),
+	}
+	for _, p := range policies {
+		rl.policies[p.Name] = p
+	}
+	return rl
+}
+
+// Limit returns a Gin middleware that applies the named policy. It panics
+// on an unknown name, like a route registered twice.
+func (rl *RateLimiter) Limit(name string) gin.HandlerFunc {
+	p, ok := rl.policies[name]
+	if !ok {
+		panic("unknown rate limit policy: " + name)
+	}
+	policyHeader := fmt.Sprintf("%d;w=%d;burst=%d", p.Limit, int(p.Period.Seconds()), p.Burst)
+
+	return func(c *gin.Context) {
+		if c.GetBool(rateLimitedKey) {
 			c.Next()
 			return
 		}
-
-		if info.count >= rl.limit {
-			rl.mu.Unlock()
+		c.Header("RateLimit-Policy", policyHeader)
+		if !rl.Allow(c, p, p.Key(c)) {
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error":   "rate limit exceeded",
 				"message": "너무 많은 요청입니다. 잠시 후 다시 시도해주세요.",
@@ -65,30 +158,82 @@ func (rl *RateLimiter) RateLimit() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
-
-		info.count++
-		rl.mu.Unlock()
 		c.Next()
 	}
 }
 
-// cleanup removes expired entries every minute
-func (rl *RateLimiter) cleanup() {
-	ticker := time.NewTicker(time.Minute)
-	defer ticker.Stop()
+// Allow spends a token from key's bucket under p and sets the RateLimit
+// headers, plus Retry-After when the bucket is empty. p need not be one
+// of the limiter's named policies, so callers can limit per client.
+func (rl *RateLimiter) Allow(c *gin.Context, p RateLimitPolicy, key string) bool {
+	remaining, reset, retryAfter := rl.take(p, key)
+
+	c.Header("RateLimit-Limit", strconv.Itoa(p.Burst))
+	c.Header("RateLimit-Remaining", strconv.Itoa(remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(reset)))
+	if retryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+		return false
+	}
+	return true
+}
+
+// take spends a token from key's bucket. It returns the whole tokens
+// left, how long until the bucket is full again, and, when the bucket
+// was empty, how long until the next token.
+func (rl *RateLimiter) take(p RateLimitPolicy, key string) (remaining int, reset, retryAfter time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	rl.sweep(now)
+
+	burst := float64(p.Burst)
+	perToken := p.Period / time.Duration(p.Limit)
+	id := p.Name + "|" + key
+	b, ok := rl.buckets[id]
+	if !ok {
+		b = &bucket{tokens: burst, updated: now}
+		rl.buckets[id] = b
+	} else if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens += float64(elapsed) / float64(perToken)
+		b.updated = now
+	}
+	// A policy may change between requests, e.g. an API key's limit
+	b.burst, b.perToken = burst, perToken
+	if b.tokens > burst {
+		b.tokens = burst
+	}
+
+	if b.tokens < 1 {
+		retryAfter = time.Duration((1 - b.tokens) * float64(perToken))
+	} else {
+		b.tokens--
+	}
+	reset = time.Duration((burst - b.tokens) * float64(perToken))
+	return int(b.tokens), reset, retryAfter
+}
 
-	for range ticker.C {
-		rl.mu.Lock()
-		now := time.Now()
-		for ip, info := range rl.requests {
-			if now.Sub(info.startTime) > rl.window {
-				delete(rl.requests, ip)
-			}
+// sweep drops buckets that have refilled, about once a minute. A full
+// bucket is the same as a missing one.
+func (rl *RateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < time.Minute {
+		return
+	}
+	rl.lastSweep = now
+	for id, b := range rl.buckets {
+		refill := time.Duration((b.burst - b.tokens) * float64(b.perToken))
+		if now.Sub(b.updated) >= refill {
+			delete(rl.buckets, id)
 		}
-		rl.mu.Unlock()
 	}
 }
 
+// ceilSeconds rounds a wait up to whole seconds for the headers
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
 // DailyAPILimiter tracks daily API usage (for Kakao Directions API)
 type DailyAPILimiter struct {
 	count     int
@@ -132,6 +277,22 @@ func (d *DailyAPILimiter) GetUsage() (int, int) {
 	return d.count, d.limit
 }
 
+// ResetAt returns when the daily count next starts over
+func (d *DailyAPILimiter) ResetAt() time.Time {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.resetTime
+}
+
+// Reset clears today's count, e.g. after an operator raised the quota
+// with the provider
+func (d *DailyAPILimiter) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.count = 0
+	d.resetTime = nextMidnight()
+}
+
 // nextMidnight returns the next midnight time
 func nextMidnight() time.Time {
 	now := time.Now()
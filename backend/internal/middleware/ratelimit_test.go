@@ -0,0 +1,312 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// fakeClock is a clock that only moves when told to
+type fakeClock struct {
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (f *fakeClock) Now() time.Time          { return f.now }
+func (f *fakeClock) Advance(d time.Duration) { f.now = f.now.Add(d) }
+
+func keyByHeader(c *gin.Context) string { return c.GetHeader("X-Test-Key") }
+
+func okHandler(c *gin.Context) { c.Status(http.StatusOK) }
+
+func serve(r *gin.Engine, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// newLimitedEngine serves GET /limited under a policy of 60 requests a
+// minute, one token a second, with bursts of 3
+func newLimitedEngine(clock *fakeClock) *gin.Engine {
+	p := RateLimitPolicy{Name: "test", Limit: 60, Period: time.Minute, Burst: 3, Key: keyByHeader}
+	rl := NewRateLimiter(clock.Now, p)
+
+	r := gin.New()
+	r.GET("/limited", rl.Limit("test"), okHandler)
+	r.GET("/exempt", func(c *gin.Context) { MarkRateLimited(c) }, rl.Limit("test"), okHandler)
+	return r
+}
+
+func limitedRequest(path, key string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("X-Test-Key", key)
+	return req
+}
+
+func TestRateLimitBurstRefillAndHeaders(t *testing.T) {
+	clock := newFakeClock()
+	r := newLimitedEngine(clock)
+
+	steps := []struct {
+		advance    time.Duration
+		status     int
+		remaining  string
+		reset      string
+		retryAfter string
+	}{
+		// The full burst goes through back to back
+		{0, http.StatusOK, "2", "1", ""},
+		{0, http.StatusOK, "1", "2", ""},
+		{0, http.StatusOK, "0", "3", ""},
+		// Then the bucket is empty until the next token
+		{0, http.StatusTooManyRequests, "0", "3", "1"},
+		{500 * time.Millisecond, http.StatusTooManyRequests, "0", "3", "1"},
+		// One token a second
+		{500 * time.Millisecond, http.StatusOK, "0", "3", ""},
+		{time.Second, http.StatusOK, "0", "3", ""},
+		// A long pause refills the bucket up to the burst, not beyond
+		{time.Hour, http.StatusOK, "2", "1", ""},
+	}
+	for i, s := range steps {
+		clock.Advance(s.advance)
+		w := serve(r, limitedRequest("/limited", "a"))
+
+		if w.Code != s.status {
+			t.Errorf("step %d: status %d, want %d", i, w.Code, s.status)
+		}
+		h := w.Header()
+		if got := h.Get("RateLimit-Limit"); got != "3" {
+			t.Errorf("step %d: RateLimit-Limit %q, want 3", i, got)
+		}
+		if got := h.Get("RateLimit-Remaining"); got != s.remaining {
+			t.Errorf("step %d: RateLimit-Remaining %q, want %q", i, got, s.remaining)
+		}
+		if got := h.Get("RateLimit-Reset"); got != s.reset {
+			t.Errorf("step %d: RateLimit-Reset %q, want %q", i, got, s.reset)
+		}
+		if got := h.Get("Retry-After"); got != s.retryAfter {
+			t.Errorf("step %d: Retry-After %q, want %q", i, got, s.retryAfter)
+		}
+		if got := h.Get("RateLimit-Policy"); got != "60;w=60;burst=3" {
+			t.Errorf("step %d: RateLimit-Policy %q", i, got)
+		}
+	}
+}
+
+func TestRateLimitKeysAreIndependent(t *testing.T) {
+	clock := newFakeClock()
+	r := newLimitedEngine(clock)
+
+	for i := 0; i < 3; i++ {
+		serve(r, limitedRequest("/limited", "a"))
+	}
+	if w := serve(r, limitedRequest("/limited", "a")); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("key a: status %d, want 429", w.Code)
+	}
+	if w := serve(r, limitedRequest("/limited", "b")); w.Code != http.StatusOK {
+		t.Fatalf("key b: status %d, want 200", w.Code)
+	}
+}
+
+func TestRateLimitSkipsMarkedRequests(t *testing.T) {
+	clock := newFakeClock()
+	r := newLimitedEngine(clock)
+
+	for i := 0; i < 10; i++ {
+		w := serve(r, limitedRequest("/exempt", "a"))
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: status %d, want 200", i, w.Code)
+		}
+		if w.Header().Get("RateLimit-Remaining") != "" {
+			t.Fatalf("request %d: marked request was counted", i)
+		}
+	}
+}
+
+func TestRateLimitSweepKeepsPartialBuckets(t *testing.T) {
+	clock := newFakeClock()
+	// One token a minute, so an empty bucket takes three minutes to refill
+	p := RateLimitPolicy{Name: "slow", Limit: 1, Period: time.Minute, Burst: 3, Key: keyByHeader}
+	rl := NewRateLimiter(clock.Now, p)
+	r := gin.New()
+	r.GET("/limited", rl.Limit("slow"), okHandler)
+
+	for i := 0; i < 3; i++ {
+		serve(r, limitedRequest("/limited", "a"))
+	}
+	// Another key's request triggers a sweep, which must keep a's bucket
+	clock.Advance(61 * time.Second)
+	serve(r, limitedRequest("/limited", "b"))
+
+	w := serve(r, limitedRequest("/limited", "a"))
+	if w.Code != http.StatusOK || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("status %d, RateLimit-Remaining %q; want 200 with 0 left",
+			w.Code, w.Header().Get("RateLimit-Remaining"))
+	}
+	if w := serve(r, limitedRequest("/limited", "a")); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status %d, want 429", w.Code)
+	}
+}
+
+func TestParseRateLimitPolicy(t *testing.T) {
+	tests := []struct {
+		spec    string
+		limit   int
+		period  time.Duration
+		burst   int
+		wantErr bool
+	}{
+		{spec: "100/1m/30", limit: 100, period: time.Minute, burst: 30},
+		{spec: "5/1s", limit: 5, period: time.Second, burst: 5},
+		{spec: "", wantErr: true},
+		{spec: "100", wantErr: true},
+		{spec: "0/1m", wantErr: true},
+		{spec: "-1/1m", wantErr: true},
+		{spec: "10/0s", wantErr: true},
+		{spec: "10/soon", wantErr: true},
+		{spec: "10/1m/0", wantErr: true},
+		{spec: "10/1m/5/1", wantErr: true},
+	}
+	for _, tt := range tests {
+		p, err := ParseRateLimitPolicy("test", tt.spec, KeyByIP)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error", tt.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.spec, err)
+			continue
+		}
+		if p.Limit != tt.limit || p.Period != tt.period || p.Burst != tt.burst {
+			t.Errorf("%q: got %d/%v/%d", tt.spec, p.Limit, p.Period, p.Burst)
+		}
+	}
+}
+
+func TestKeyByIPBehindProxies(t *testing.T) {
+	local := []string{"127.0.0.1", "::1"}
+	tests := []struct {
+		name    string
+		proxies []string
+		remote  string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name:    "forged X-Forwarded-For from an untrusted peer",
+			proxies: local,
+			remote:  "203.0.113.5:4000",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.7"},
+			want:    "ip:203.0.113.5",
+		},
+		{
+			name:    "forged left-most entry behind a trusted proxy",
+			proxies: local,
+			remote:  "127.0.0.1:4000",
+			headers: map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.7"},
+			want:    "ip:198.51.100.7",
+		},
+		{
+			name:    "chain of trusted proxies",
+			proxies: []string{"127.0.0.1", "10.0.0.0/8"},
+			remote:  "127.0.0.1:4000",
+			headers: map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.7, 10.0.0.2"},
+			want:    "ip:198.51.100.7",
+		},
+		{
+			name:    "X-Real-IP only, from a trusted proxy",
+			proxies: local,
+			remote:  "127.0.0.1:4000",
+			headers: map[string]string{"X-Real-IP": "198.51.100.9"},
+			want:    "ip:198.51.100.9",
+		},
+		{
+			name:    "X-Real-IP only, from an untrusted peer",
+			proxies: local,
+			remote:  "203.0.113.5:4000",
+			headers: map[string]string{"X-Real-IP": "198.51.100.9"},
+			want:    "ip:203.0.113.5",
+		},
+		{
+			name:    "empty TRUSTED_PROXIES trusts no one",
+			proxies: nil,
+			remote:  "127.0.0.1:4000",
+			headers: map[string]string{"X-Forwarded-For": "198.51.100.7", "X-Real-IP": "198.51.100.9"},
+			want:    "ip:127.0.0.1",
+		},
+		{
+			name:    "IPv6 peer counts per /64",
+			proxies: local,
+			remote:  "[2001:db8:1:2::1]:4000",
+			want:    "ip:2001:db8:1:2::/64",
+		},
+		{
+			name:    "another IPv6 address in the same /64",
+			proxies: local,
+			remote:  "[2001:db8:1:2:ffff:ffff:ffff:5]:4000",
+			want:    "ip:2001:db8:1:2::/64",
+		},
+		{
+			name:    "IPv6 address in a different /64",
+			proxies: local,
+			remote:  "[2001:db8:1:3::1]:4000",
+			want:    "ip:2001:db8:1:3::/64",
+		},
+		{
+			name:    "IPv6 client forwarded by a trusted proxy",
+			proxies: local,
+			remote:  "[::1]:4000",
+			headers: map[string]string{"X-Forwarded-For": "2001:db8:1:2::abcd"},
+			want:    "ip:2001:db8:1:2::/64",
+		},
+		{
+			name:    "IPv4-mapped IPv6 peer counts as IPv4",
+			proxies: local,
+			remote:  "[::ffff:198.51.100.7]:4000",
+			want:    "ip:198.51.100.7",
+		},
+		{
+			name:    "IPv4-mapped IPv6 client forwarded by a trusted proxy",
+			proxies: local,
+			remote:  "127.0.0.1:4000",
+			headers: map[string]string{"X-Forwarded-For": "::ffff:198.51.100.7"},
+			want:    "ip:198.51.100.7",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			if err := TrustProxies(r, tt.proxies); err != nil {
+				t.Fatal(err)
+			}
+			r.GET("/key", func(c *gin.Context) { c.String(http.StatusOK, KeyByIP(c)) })
+
+			req := httptest.NewRequest(http.MethodGet, "/key", nil)
+			req.RemoteAddr = tt.remote
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			if got := serve(r, req).Body.String(); got != tt.want {
+				t.Errorf("key %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrustProxiesRejectsInvalidEntries(t *testing.T) {
+	if err := TrustProxies(gin.New(), []string{"10.0.0.0/33"}); err == nil {
+		t.Fatal("expected an error for an invalid CIDR")
+	}
+}
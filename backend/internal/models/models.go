@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 // SearchCache represents a cached search result
 type SearchCache struct {
@@ -13,26 +17,203 @@ type SearchCache struct {
 
 // Favorite represents a user's favorite place
 type Favorite struct {
-	ID          int64     `json:"id"`
-	UserID      string    `json:"user_id"`
+	ID          int64         `json:"id"`
+	UserID      string        `json:"user_id"`
+	PlaceID     string        `json:"place_id"`
+	PlaceName   string        `json:"place_name"`
+	Address     string        `json:"address,omitempty"`
+	RoadAddress string        `json:"road_address,omitempty"`
+	Lat         float64       `json:"lat"`
+	Lng         float64       `json:"lng"`
+	Phone       string        `json:"phone,omitempty"`
+	Category    string        `json:"category,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+	Version     int64         `json:"version"`
+	Distance    *float64      `json:"distance,omitempty"` // meters, set when listing near a point
+	Flag        *FavoriteFlag `json:"flag,omitempty"`     // set when the place looks stale
+
+	VisitCount    int        `json:"visit_count"`
+	LastVisitedAt *time.Time `json:"last_visited_at,omitempty"`
+}
+
+// FavoritePatch represents a partial update to a favorite.
+// Nil fields are left unchanged.
+type FavoritePatch struct {
+	PlaceName   *string  `json:"place_name"`
+	Address     *string  `json:"address"`
+	RoadAddress *string  `json:"road_address"`
+	Lat         *float64 `json:"lat"`
+	Lng         *float64 `json:"lng"`
+	Phone       *string  `json:"phone"`
+	Category    *string  `json:"category"`
+}
+
+// IsEmpty reports whether the patch changes nothing
+func (p *FavoritePatch) IsEmpty() bool {
+	return p.PlaceName == nil && p.Address == nil && p.RoadAddress == nil &&
+		p.Lat == nil && p.Lng == nil && p.Phone == nil && p.Category == nil
+}
+
+// Place verification statuses as stored in place_verifications
+const (
+	VerificationFound   = "found"
+	VerificationClosed  = "closed"
+	VerificationUnknown = "unknown" // the source could not tell
+)
+
+// PlaceVerification is the latest state of a place as seen by the place source
+type PlaceVerification struct {
 	PlaceID     string    `json:"place_id"`
-	PlaceName   string    `json:"place_name"`
+	Status      string    `json:"status"` // "found", "closed" or "unknown"
+	PlaceName   string    `json:"place_name,omitempty"`
 	Address     string    `json:"address,omitempty"`
 	RoadAddress string    `json:"road_address,omitempty"`
-	Lat         float64   `json:"lat"`
-	Lng         float64   `json:"lng"`
 	Phone       string    `json:"phone,omitempty"`
 	Category    string    `json:"category,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
+	Lat         float64   `json:"lat,omitempty"`
+	Lng         float64   `json:"lng,omitempty"`
+	CheckedAt   time.Time `json:"checked_at"`
+}
+
+// Favorite flag kinds
+const (
+	FlagClosed  = "closed"
+	FlagMoved   = "moved"
+	FlagChanged = "changed"
+)
+
+// FavoriteFlag marks a favorite whose stored details no longer match the place source
+type FavoriteFlag struct {
+	Kind      string               `json:"kind"` // "closed", "moved" or "changed"
+	Diff      map[string]FieldDiff `json:"diff,omitempty"`
+	CheckedAt time.Time            `json:"checked_at"`
+}
+
+// FieldDiff holds the stored and current value of a changed field
+type FieldDiff struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// Bulk favorite operation types
+const (
+	FavoriteOpAdd    = "add"
+	FavoriteOpUpdate = "update"
+	FavoriteOpDelete = "delete"
+)
+
+// FavoriteOperation is a single operation in a bulk favorites request.
+// Add uses the patch fields as the new favorite; update applies them as a
+// partial update checked against Version (0 = current version).
+type FavoriteOperation struct {
+	Op      string `json:"op"`
+	PlaceID string `json:"place_id"`
+	FavoritePatch
+	Version int64 `json:"version,omitempty"`
+}
+
+// FavoriteOperationResult is the outcome of a single bulk operation
+type FavoriteOperationResult struct {
+	Index    int       `json:"index"`
+	Op       string    `json:"op"`
+	PlaceID  string    `json:"place_id"`
+	Status   string    `json:"status"` // "ok", "failed", "rolled_back" or "skipped"
+	Error    string    `json:"error,omitempty"`
+	Favorite *Favorite `json:"favorite,omitempty"`
 }
 
-// SearchHistory represents a user's search history entry
+// SearchHistory represents a keyword a user has searched.
+// Repeated searches of the same normalized keyword share one entry.
 type SearchHistory struct {
-	ID          int64     `json:"id"`
-	UserID      string    `json:"user_id"`
-	Keyword     string    `json:"keyword"`
-	ResultCount int       `json:"result_count"`
-	SearchedAt  time.Time `json:"searched_at"`
+	ID              int64     `json:"id"`
+	UserID          string    `json:"user_id"`
+	Keyword         string    `json:"keyword"` // as most recently typed
+	Count           int       `json:"count"`
+	ResultCount     int       `json:"result_count"` // of the most recent search
+	FirstSearchedAt time.Time `json:"first_searched_at"`
+	LastSearchedAt  time.Time `json:"last_searched_at"`
+}
+
+// TrendingKeyword is a keyword ranked by recent search velocity
+type TrendingKeyword struct {
+	Keyword      string  `json:"keyword"`
+	Rank         int     `json:"rank"`
+	Score        float64 `json:"score"` // decayed search count
+	Count        int     `json:"count"` // raw searches within the window
+	PreviousRank *int    `json:"previous_rank"`
+	RankChange   *int    `json:"rank_change"` // positive = moved up, nil = new entry
+}
+
+// Visit represents a logged visit to a place
+type Visit struct {
+	ID        int64     `json:"id"`
+	UserID    string    `json:"user_id"`
+	PlaceID   string    `json:"place_id"`
+	PlaceName string    `json:"place_name"`
+	Category  string    `json:"category,omitempty"`
+	Lat       float64   `json:"lat,omitempty"`
+	Lng       float64   `json:"lng,omitempty"`
+	VisitedAt time.Time `json:"visited_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PlaceVisitSummary aggregates a user's visits to one place
+type PlaceVisitSummary struct {
+	PlaceID       string    `json:"place_id"`
+	PlaceName     string    `json:"place_name"`
+	Category      string    `json:"category,omitempty"`
+	Count         int       `json:"count"`
+	LastVisitedAt time.Time `json:"last_visited_at"`
+}
+
+// MonthlyCategoryVisits counts visits in one month for one category
+type MonthlyCategoryVisits struct {
+	Month    string `json:"month"` // YYYY-MM
+	Category string `json:"category"`
+	Count    int    `json:"count"`
+}
+
+// VisitStats summarizes a user's visits over a period
+type VisitStats struct {
+	Total     int                     `json:"total"`
+	Monthly   []MonthlyCategoryVisits `json:"monthly"`
+	TopPlaces []PlaceVisitSummary     `json:"top_places"`
+}
+
+// SharedList is a published selection of a user's favorites, as seen by its owner
+type SharedList struct {
+	ID           int64      `json:"id"`
+	OwnerID      string     `json:"owner_id"`
+	Slug         string     `json:"slug"`
+	Title        string     `json:"title"`
+	Description  string     `json:"description,omitempty"`
+	AllFavorites bool       `json:"all_favorites"`
+	PlaceIDs     []string   `json:"place_ids,omitempty"` // empty when AllFavorites
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+}
+
+// PublicList is the read-only view of a shared list served to anyone with the slug
+type PublicList struct {
+	Slug        string        `json:"slug"`
+	Title       string        `json:"title"`
+	Description string        `json:"description,omitempty"`
+	Places      []SharedPlace `json:"places"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+// SharedPlace is a favorite without owner-specific fields
+type SharedPlace struct {
+	PlaceID     string  `json:"place_id"`
+	PlaceName   string  `json:"place_name"`
+	Address     string  `json:"address,omitempty"`
+	RoadAddress string  `json:"road_address,omitempty"`
+	Lat         float64 `json:"lat"`
+	Lng         float64 `json:"lng"`
+	Phone       string  `json:"phone,omitempty"`
+	Category    string  `json:"category,omitempty"`
 }
 
 // Place represents a Kakao place search result
@@ -51,8 +232,191 @@ type Place struct {
 	Distance          string `json:"distance"`
 }
 
+// User is a logged-in account
+type User struct {
+	ID              int64     `json:"id"`
+	Provider        string    `json:"provider"`
+	Nickname        string    `json:"nickname"`
+	ProfileImageURL string    `json:"profile_image_url,omitempty"`
+	IsAdmin         bool      `json:"is_admin"`
+	CreatedAt       time.Time `json:"created_at"`
+	LastLoginAt     time.Time `json:"last_login_at"`
+}
+
+// UserLookup is an account as seen by an operator
+type UserLookup struct {
+	User
+	Subject        string `json:"subject"`
+	ProviderUserID string `json:"provider_user_id"`
+	Favorites      int    `json:"favorites"`
+	History        int    `json:"history"`
+	ActiveSessions int    `json:"active_sessions"`
+}
+
+// Subject is the user ID under which the account's data is stored,
+// the counterpart of the anonymous cookie identity
+func (u *User) Subject() string {
+	return fmt.Sprintf("user_%d", u.ID)
+}
+
+// Session is a login on one device
+type Session struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"-"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// MergeSummary reports what an anonymous identity's data contributed
+// to an account when it logged in
+type MergeSummary struct {
+	Favorites       int `json:"favorites"`        // moved to the account
+	FavoritesMerged int `json:"favorites_merged"` // already favorited by the account
+	History         int `json:"history"`
+	HistoryMerged   int `json:"history_merged"`
+	SearchEvents    int `json:"search_events"`
+	Visits          int `json:"visits"`
+	SharedLists     int `json:"shared_lists"`
+}
+
+// Empty reports whether nothing was merged
+func (m *MergeSummary) Empty() bool {
+	return *m == MergeSummary{}
+}
+
+// Deletion job statuses
+const (
+	DeletionPending = "pending"
+	DeletionRunning = "running"
+	DeletionDone    = "done"
+	DeletionFailed  = "failed"
+)
+
+// DeletionJob tracks the erasure of a user's personal data
+type DeletionJob struct {
+	ID             string     `json:"id"`
+	Status         string     `json:"status"`
+	DeletedRows    int64      `json:"deleted_rows"`
+	AnonymizedRows int64      `json:"anonymized_rows"`
+	Error          string     `json:"error,omitempty"`
+	RequestedAt    time.Time  `json:"requested_at"`
+	StartedAt      *time.Time `json:"started_at,omitempty"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+}
+
+// API key scopes
+const (
+	ScopeReadPlaces     = "read:places"
+	ScopeReadPopular    = "read:popular"
+	ScopeWriteFavorites = "write:favorites"
+)
+
+// APIKeyScopes lists every scope a key can be granted
+var APIKeyScopes = []string{ScopeReadPlaces, ScopeReadPopular, ScopeWriteFavorites}
+
+// APIKey is a credential issued to a third-party client. The key itself
+// is only shown once, when it is issued.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"` // first characters of the key, for recognizing it
+	Scopes     []string   `json:"scopes"`
+	RateLimit  int        `json:"rate_limit"`  // requests per minute
+	DailyQuota int        `json:"daily_quota"` // requests per day (KST)
+	UsedToday  int        `json:"used_today"`
+	CreatedBy  string     `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// HasScope reports whether the key was granted scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Subject is the user ID under which data written with the key is stored
+func (k *APIKey) Subject() string {
+	return fmt.Sprintf("key_%d", k.ID)
+}
+
+// AuditEntry records an administrative or destructive operation
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	Actor     string          `json:"actor"`
+	IP        string          `json:"ip,omitempty"`
+	Action    string          `json:"action"`
+	Target    string          `json:"target,omitempty"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Keyword moderation statuses
+const (
+	KeywordHidden = "hidden"
+	KeywordPinned = "pinned"
+)
+
+// KeywordModeration is an admin decision about a public keyword
+type KeywordModeration struct {
+	Keyword           string    `json:"keyword"`
+	NormalizedKeyword string    `json:"normalized_keyword"`
+	Status            string    `json:"status"`
+	Note              string    `json:"note,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// LowResultKeyword is a keyword that repeatedly returned few or no results
+type LowResultKeyword struct {
+	Keyword         string      `json:"keyword"` // most recent spelling
+	Normalized      string      `json:"normalized_keyword"`
+	Searches        int         `json:"searches"`
+	ZeroResults     int         `json:"zero_results"`
+	Users           int         `json:"users"`
+	MaxResultCount  int         `json:"max_result_count"`
+	FirstSearchedAt time.Time   `json:"first_searched_at"`
+	LastSearchedAt  time.Time   `json:"last_searched_at"`
+	Samples         []time.Time `json:"samples"` // most recent search times
+}
+
+// Place catalog sources
+const (
+	PlaceSourceSearch   = "search"
+	PlaceSourceFavorite = "favorite"
+)
+
+// PlaceDetail is a place from the local catalog with usage information
+type PlaceDetail struct {
+	Place
+	Source        string            `json:"source"`
+	FirstSeen     time.Time         `json:"first_seen"`
+	LastSeen      time.Time         `json:"last_seen"`
+	FavoriteCount int               `json:"favorite_count"`
+	IsFavorite    bool              `json:"is_favorite"`
+	Appearances   []PlaceAppearance `json:"appearances"`
+}
+
+// PlaceAppearance is a search keyword whose results included a place
+type PlaceAppearance struct {
+	Keyword   string    `json:"keyword"`
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
 // PopularKeyword represents a frequently searched keyword
 type PopularKeyword struct {
 	Keyword string `json:"keyword"`
 	Count   int    `json:"count"`
+	Pinned  bool   `json:"pinned,omitempty"`
 }
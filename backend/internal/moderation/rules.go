@@ -0,0 +1,92 @@
+package moderation
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/jju-compass/jju-compass-map/internal/textnorm"
+)
+
+// defaultPatterns keep personal information off public keyword lists
+var defaultPatterns = []string{
+	`01[016789][-. ]?\d{3,4}[-. ]?\d{4}`, // mobile phone numbers
+	`[\w.+-]+@[\w-]+\.[\w.-]+`,           // email addresses
+}
+
+// Rules decides which keywords may be shown publicly. A term blocks every
+// keyword containing it, compared by textnorm.Key so spacing and case do
+// not get around it. Patterns are matched against textnorm.Normalize.
+// A nil *Rules blocks nothing.
+type Rules struct {
+	terms    []string
+	patterns []*regexp.Regexp
+}
+
+// Compile builds rules from blocklist entries. An entry written as
+// /regexp/ is a pattern, anything else a term. The default personal
+// information patterns are always included.
+func Compile(entries []string) (*Rules, error) {
+	r := &Rules{}
+	for _, p := range defaultPatterns {
+		r.patterns = append(r.patterns, regexp.MustCompile(p))
+	}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if len(entry) > 2 && strings.HasPrefix(entry, "/") && strings.HasSuffix(entry, "/") {
+			re, err := regexp.Compile(entry[1 : len(entry)-1])
+			if err != nil {
+				return nil, fmt.Errorf("blocklist pattern %s: %w", entry, err)
+			}
+			r.patterns = append(r.patterns, re)
+			continue
+		}
+		if term := textnorm.Key(entry); term != "" {
+			r.terms = append(r.terms, term)
+		}
+	}
+	return r, nil
+}
+
+// ReadFile reads blocklist entries from a file, one per line.
+// Blank lines and lines starting with # are ignored.
+func ReadFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	return entries, scanner.Err()
+}
+
+// Blocked reports whether keyword must not be shown publicly
+func (r *Rules) Blocked(keyword string) bool {
+	if r == nil {
+		return false
+	}
+	key := textnorm.Key(keyword)
+	for _, term := range r.terms {
+		if strings.Contains(key, term) {
+			return true
+		}
+	}
+	normalized := textnorm.Normalize(keyword)
+	for _, re := range r.patterns {
+		if re.MatchString(normalized) {
+			return true
+		}
+	}
+	return false
+}
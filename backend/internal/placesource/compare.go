@@ -0,0 +1,64 @@
+package placesource
+
+import (
+	"strings"
+
+	"github.com/jju-compass/jju-compass-map/internal/geo"
+	"github.com/jju-compass/jju-compass-map/internal/models"
+)
+
+// movedThreshold is the distance in meters beyond which a place counts as moved
+const movedThreshold = 100.0
+
+// Compare flags a favorite whose stored details differ from the latest
+// verification. It returns nil when the favorite is up to date.
+func Compare(f *models.Favorite, v *models.PlaceVerification) *models.FavoriteFlag {
+	if v == nil || v.Status == models.VerificationUnknown {
+		return nil
+	}
+	if v.Status == models.VerificationClosed {
+		return &models.FavoriteFlag{Kind: models.FlagClosed, CheckedAt: v.CheckedAt}
+	}
+
+	diff := map[string]models.FieldDiff{}
+	moved := false
+
+	distance := geo.Distance(geo.Point{Lat: f.Lat, Lng: f.Lng}, geo.Point{Lat: v.Lat, Lng: v.Lng})
+	if distance > movedThreshold {
+		moved = true
+		diff["lat"] = models.FieldDiff{Old: f.Lat, New: v.Lat}
+		diff["lng"] = models.FieldDiff{Old: f.Lng, New: v.Lng}
+	}
+	if changed(f.RoadAddress, v.RoadAddress) {
+		moved = true
+		diff["road_address"] = models.FieldDiff{Old: f.RoadAddress, New: v.RoadAddress}
+	}
+	if changed(f.Address, v.Address) {
+		moved = true
+		diff["address"] = models.FieldDiff{Old: f.Address, New: v.Address}
+	}
+	if changed(f.PlaceName, v.PlaceName) {
+		diff["place_name"] = models.FieldDiff{Old: f.PlaceName, New: v.PlaceName}
+	}
+	if changed(f.Phone, v.Phone) {
+		diff["phone"] = models.FieldDiff{Old: f.Phone, New: v.Phone}
+	}
+	if changed(f.Category, v.Category) {
+		diff["category"] = models.FieldDiff{Old: f.Category, New: v.Category}
+	}
+
+	if len(diff) == 0 {
+		return nil
+	}
+	kind := models.FlagChanged
+	if moved {
+		kind = models.FlagMoved
+	}
+	return &models.FavoriteFlag{Kind: kind, Diff: diff, CheckedAt: v.CheckedAt}
+}
+
+// changed reports whether a field differs, ignoring values the source omits
+func changed(stored, current string) bool {
+	current = strings.TrimSpace(current)
+	return current != "" && strings.TrimSpace(stored) != current
+}
@@ -0,0 +1,109 @@
+package placesource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/jju-compass/jju-compass-map/internal/models"
+)
+
+// searchRadius is the radius in meters searched around the stored location
+const searchRadius = 2000
+
+// KakaoSource looks places up through the Kakao Local keyword search API.
+// Kakao has no lookup by place ID, so the place is searched by its stored
+// name near its stored location, then by its stored address, and matched
+// by ID. A place found by neither search is reported as gone, but only
+// when both searches returned all their results; otherwise it is unknown.
+type KakaoSource struct {
+	baseURL    string
+	apiKey     string
+	budget     Budget
+	httpClient *http.Client
+}
+
+// NewKakaoSource creates a Kakao Local API source. Every search request
+// is charged to budget.
+func NewKakaoSource(baseURL, apiKey string, budget Budget) *KakaoSource {
+	return &KakaoSource{
+		baseURL:    baseURL,
+		apiKey:     apiKey,
+		budget:     budget,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Lookup finds the place by ID among keyword search results
+func (s *KakaoSource) Lookup(ctx context.Context, ref Ref) (*models.Place, error) {
+	queries := []string{ref.PlaceName}
+	if ref.RoadAddress != "" {
+		queries = append(queries, ref.RoadAddress)
+	} else if ref.Address != "" {
+		queries = append(queries, ref.Address)
+	}
+
+	complete := true
+	for _, query := range queries {
+		places, isEnd, err := s.search(ctx, query, ref.Lat, ref.Lng)
+		if err != nil {
+			return nil, err
+		}
+		for i := range places {
+			if places[i].ID == ref.PlaceID {
+				return &places[i], nil
+			}
+		}
+		// Only the first page is read; the place may be on a later one
+		complete = complete && isEnd
+	}
+	if !complete {
+		return nil, ErrUnknown
+	}
+	return nil, nil
+}
+
+// search runs a keyword search around a location, returning the first
+// page of results and whether it was the last
+func (s *KakaoSource) search(ctx context.Context, query string, lat, lng float64) ([]models.Place, bool, error) {
+	if !s.budget.Allow() {
+		return nil, false, ErrBudgetExhausted
+	}
+
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("x", strconv.FormatFloat(lng, 'f', -1, 64))
+	params.Set("y", strconv.FormatFloat(lat, 'f', -1, 64))
+	params.Set("radius", strconv.Itoa(searchRadius))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", s.baseURL+"/v2/local/search/keyword.json?"+params.Encode(), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Authorization", "KakaoAK "+s.apiKey)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("%w: kakao status %d", ErrUnavailable, resp.StatusCode)
+	}
+
+	var body struct {
+		Documents []models.Place `json:"documents"`
+		Meta      struct {
+			IsEnd bool `json:"is_end"`
+		} `json:"meta"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, false, err
+	}
+	return body.Documents, body.Meta.IsEnd, nil
+}
@@ -0,0 +1,67 @@
+package placesource
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/jju-compass/jju-compass-map/internal/models"
+)
+
+// ErrUnavailable is returned when a source cannot tell whether a place exists
+var ErrUnavailable = errors.New("place source unavailable")
+
+// ErrUnknown is returned when a source searched for a place without
+// finding it, but did not see every result, so it may still exist
+var ErrUnknown = errors.New("place not found among the results checked")
+
+// ErrBudgetExhausted is returned when a source has used up its daily
+// request budget
+var ErrBudgetExhausted = errors.New("place source budget exhausted")
+
+// Budget limits the requests a source makes to a paid API
+type Budget interface {
+	Allow() bool
+}
+
+// Ref identifies a place to look up, with the details we last stored for it
+type Ref struct {
+	PlaceID     string
+	PlaceName   string
+	Address     string
+	RoadAddress string
+	Lat         float64
+	Lng         float64
+}
+
+// Source looks up the current state of a place.
+// Lookup returns nil without error when the place no longer exists.
+type Source interface {
+	Lookup(ctx context.Context, ref Ref) (*models.Place, error)
+}
+
+// UnknownVerification records that a place could not be confirmed either
+// way, so that it is not checked again before it is due
+func UnknownVerification(placeID string) models.PlaceVerification {
+	return models.PlaceVerification{PlaceID: placeID, Status: models.VerificationUnknown}
+}
+
+// ToVerification converts a lookup result into a stored verification
+func ToVerification(placeID string, place *models.Place) models.PlaceVerification {
+	if place == nil {
+		return models.PlaceVerification{PlaceID: placeID, Status: models.VerificationClosed}
+	}
+	lat, _ := strconv.ParseFloat(place.Y, 64)
+	lng, _ := strconv.ParseFloat(place.X, 64)
+	return models.PlaceVerification{
+		PlaceID:     placeID,
+		Status:      models.VerificationFound,
+		PlaceName:   place.PlaceName,
+		Address:     place.AddressName,
+		RoadAddress: place.RoadAddressName,
+		Phone:       place.Phone,
+		Category:    place.CategoryName,
+		Lat:         lat,
+		Lng:         lng,
+	}
+}
@@ -0,0 +1,37 @@
+package placesource
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+
+	"github.com/jju-compass/jju-compass-map/internal/models"
+)
+
+// StubSource serves lookups from a JSON file mapping place IDs to places.
+// A null entry means the place is gone; a missing entry is not checked.
+type StubSource struct {
+	places map[string]*models.Place
+}
+
+// NewStubSource loads a stub source from a JSON file
+func NewStubSource(path string) (*StubSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var places map[string]*models.Place
+	if err := json.Unmarshal(data, &places); err != nil {
+		return nil, err
+	}
+	return &StubSource{places: places}, nil
+}
+
+// Lookup returns the stubbed place
+func (s *StubSource) Lookup(ctx context.Context, ref Ref) (*models.Place, error) {
+	place, ok := s.places[ref.PlaceID]
+	if !ok {
+		return nil, ErrUnavailable
+	}
+	return place, nil
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/jju-compass/jju-compass-map/internal/models"
+)
+
+// APIKeyPrefix starts every API key, telling keys apart from other
+// bearer tokens
+const APIKeyPrefix = "jjk_"
+
+// apiKeyDisplayLength is how much of a key is kept in clear for display
+const apiKeyDisplayLength = len(APIKeyPrefix) + 6
+
+// APIKeyRepository handles API keys for third-party clients
+type APIKeyRepository struct {
+	db *sql.DB
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// apiKeyColumns is the column list read by scanAPIKey
+const apiKeyColumns = `k.id, k.name, k.prefix, k.scopes, k.rate_limit, k.daily_quota,
+	k.created_by, k.created_at, k.last_used_at, k.revoked_at`
+
+// scanAPIKey reads a key selected with apiKeyColumns, plus any extra
+// destinations that follow them
+func scanAPIKey(s rowScanner, extra ...interface{}) (models.APIKey, error) {
+	var k models.APIKey
+	var scopes string
+	var createdBy sql.NullString
+	var lastUsed, revoked sql.NullTime
+	dest := append([]interface{}{&k.ID, &k.Name, &k.Prefix, &scopes, &k.RateLimit, &k.DailyQuota,
+		&createdBy, &k.CreatedAt, &lastUsed, &revoked}, extra...)
+	if err := s.Scan(dest...); err != nil {
+		return k, err
+	}
+	k.Scopes = strings.Fields(scopes)
+	k.CreatedBy = createdBy.String
+	if lastUsed.Valid {
+		k.LastUsedAt = &lastUsed.Time
+	}
+	if revoked.Valid {
+		k.RevokedAt = &revoked.Time
+	}
+	return k, nil
+}
+
+// Issue creates a key and returns it along with the secret key, which is
+// not stored and cannot be shown again
+func (r *APIKeyRepository) Issue(name string, scopes []string, rateLimit, dailyQuota int, createdBy string) (*models.APIKey, string, error) {
+	slug, err := newSlug()
+	if err != nil {
+		return nil, "", err
+	}
+	key := APIKeyPrefix + slug
+
+	var id int64
+	err = r.db.QueryRow(`
+		INSERT INTO api_keys (name, prefix, key_hash, scopes, rate_limit, daily_quota, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, name, key[:apiKeyDisplayLength], hashToken(key), strings.Join(scopes, " "),
+		rateLimit, dailyQuota, createdBy).Scan(&id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	k, err := scanAPIKey(r.db.QueryRow(`SELECT `+apiKeyColumns+` FROM api_keys k WHERE k.id = ?`, id))
+	if err != nil {
+		return nil, "", err
+	}
+	return &k, key, nil
+}
+
+// List returns all keys, newest first, with their usage on day
+func (r *APIKeyRepository) List(day string) ([]models.APIKey, error) {
+	rows, err := r.db.Query(`
+		SELECT `+apiKeyColumns+`, COALESCE(u.count, 0)
+		FROM api_keys k
+		LEFT JOIN api_key_usage u ON u.key_id = k.id AND u.day = ?
+		ORDER BY k.id DESC
+	`, day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		var used int
+		k, err := scanAPIKey(rows, &used)
+		if err != nil {
+			return nil, err
+		}
+		k.UsedToday = used
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke disables a key for good
+func (r *APIKeyRepository) Revoke(id int64) error {
+	result, err := r.db.Exec(`
+		UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP WHERE id = ? AND revoked_at IS NULL
+	`, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Authenticate resolves a key to its record, or nil if it is unknown or
+// revoked
+func (r *APIKeyRepository) Authenticate(key string) (*models.APIKey, error) {
+	k, err := scanAPIKey(r.db.QueryRow(`
+		SELECT `+apiKeyColumns+` FROM api_keys k WHERE k.key_hash = ? AND k.revoked_at IS NULL
+	`, hashToken(key)))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// RecordUse counts a request against the key's quota for day and stamps
+// last_used_at. It returns the count so far and false, without counting,
+// once the quota is used up.
+func (r *APIKeyRepository) RecordUse(k *models.APIKey, day string) (int, bool, error) {
+	var used int
+	err := withTx(r.db, func(tx *sql.Tx) error {
+		err := tx.QueryRow(`
+			INSERT INTO api_key_usage (key_id, day, count) VALUES (?, ?, 1)
+			ON CONFLICT(key_id, day) DO UPDATE SET count = count + 1
+			WHERE api_key_usage.count < ?
+			RETURNING count
+		`, k.ID, day, k.DailyQuota).Scan(&used)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE api_keys SET last_used_at = ? WHERE id = ?", sqlTime(time.Now()), k.ID)
+		return err
+	})
+	if err == sql.ErrNoRows {
+		return k.DailyQuota, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return used, true, nil
+}
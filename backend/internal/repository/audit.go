@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jju-compass/jju-compass-map/internal/models"
+)
+
+// AuditMinRetention is how long audit entries are kept at the least.
+// The audit_log_retention trigger refuses to delete younger entries.
+const AuditMinRetention = 90 * 24 * time.Hour
+
+// AuditRepository handles the append-only audit log
+type AuditRepository struct {
+	db *sql.DB
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// nullJSON stores an empty snapshot as NULL
+func nullJSON(b []byte) interface{} {
+	if len(b) == 0 {
+		return nil
+	}
+	return string(b)
+}
+
+// auditPseudonymPrefix marks actors that stand for an ordinary user
+const auditPseudonymPrefix = "subject:"
+
+// Pseudonym returns the random name subject appears under in the audit
+// log, creating it on first use. Erasing the user deletes the mapping,
+// which leaves their entries unattributable.
+func (r *AuditRepository) Pseudonym(subject string) (string, error) {
+	slug, err := newSlug()
+	if err != nil {
+		return "", err
+	}
+	var pseudonym string
+	err = r.db.QueryRow(`
+		INSERT INTO audit_subjects (subject, pseudonym) VALUES (?, ?)
+		ON CONFLICT(subject) DO UPDATE SET pseudonym = pseudonym
+		RETURNING pseudonym
+	`, subject, auditPseudonymPrefix+slug).Scan(&pseudonym)
+	return pseudonym, err
+}
+
+// FindPseudonym returns subject's audit log name, or "" if they have
+// none
+func (r *AuditRepository) FindPseudonym(subject string) (string, error) {
+	var pseudonym string
+	err := r.db.QueryRow("SELECT pseudonym FROM audit_subjects WHERE subject = ?", subject).Scan(&pseudonym)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return pseudonym, err
+}
+
+// nullString stores an empty string as NULL
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// Record appends an entry to the audit log
+func (r *AuditRepository) Record(e *models.AuditEntry) error {
+	_, err := r.db.Exec(`
+		INSERT INTO audit_log (actor, ip, action, target, before_json, after_json)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, e.Actor, nullString(e.IP), e.Action, e.Target, nullJSON(e.Before), nullJSON(e.After))
+	return err
+}
+
+// AuditListOptions filters an audit log listing
+type AuditListOptions struct {
+	Actor    string // exact actor
+	Action   string // exact action, or a prefix ending in "." such as "favorite."
+	Target   string // exact target
+	From, To time.Time
+	BeforeID int64 // entries older than this ID, for paging (0 = newest)
+	Limit    int
+}
+
+// List returns audit entries, newest first
+func (r *AuditRepository) List(opts AuditListOptions) ([]models.AuditEntry, error) {
+	query := `
+		SELECT id, actor, ip, action, target, before_json, after_json, created_at
+		FROM audit_log
+		WHERE 1 = 1`
+	var args []interface{}
+	if opts.Actor != "" {
+		query += " AND actor = ?"
+		args = append(args, opts.Actor)
+	}
+	if opts.Action != "" {
+		if opts.Action[len(opts.Action)-1] == '.' {
+			query += " AND substr(action, 1, ?) = ?"
+			args = append(args, len(opts.Action), opts.Action)
+		} else {
+			query += " AND action = ?"
+			args = append(args, opts.Action)
+		}
+	}
+	if opts.Target != "" {
+		query += " AND target = ?"
+		args = append(args, opts.Target)
+	}
+	if opts.BeforeID > 0 {
+		query += " AND id < ?"
+		args = append(args, opts.BeforeID)
+	}
+	clause, rangeArgs := timeRange("created_at", opts.From, opts.To)
+	query += clause + " ORDER BY id DESC LIMIT ?"
+	args = append(append(args, rangeArgs...), opts.Limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		var ip, target, before, after sql.NullString
+		if err := rows.Scan(&e.ID, &e.Actor, &ip, &e.Action, &target, &before, &after, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.IP = ip.String
+		e.Target = target.String
+		if before.Valid {
+			e.Before = []byte(before.String)
+		}
+		if after.Valid {
+			e.After = []byte(after.String)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// Purge removes entries older than the given time. Times within
+// AuditMinRetention are moved back so the retention trigger never fires.
+func (r *AuditRepository) Purge(before time.Time) (int64, error) {
+	if limit := time.Now().Add(-AuditMinRetention); before.After(limit) {
+		before = limit
+	}
+	result, err := r.db.Exec("DELETE FROM audit_log WHERE created_at < ?", sqlTime(before))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -36,7 +36,8 @@ func (r *CacheRepository) Get(keyword string) (*models.SearchCache, error) {
 	return &cache, nil
 }
 
-// Set stores search results in cache
+// Set stores search results in cache and records the places in the
+// place catalog
 func (r *CacheRepository) Set(keyword string, results []models.Place, ttl time.Duration) error {
 	resultsJSON, err := json.Marshal(results)
 	if err != nil {
@@ -44,16 +45,23 @@ func (r *CacheRepository) Set(keyword string, results []models.Place, ttl time.D
 	}
 
 	expiresAt := time.Now().Add(ttl)
-	_, err = r.db.Exec(`
-		INSERT INTO search_cache (keyword, results_json, expires_at) 
-		VALUES (?, ?, ?)
-		ON CONFLICT(keyword) DO UPDATE SET 
-			results_json = excluded.results_json,
-			cached_at = CURRENT_TIMESTAMP,
-			expires_at = excluded.expires_at
-	`, keyword, string(resultsJSON), expiresAt)
-
-	return err
+	return withTx(r.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO search_cache (keyword, results_json, expires_at) 
+			VALUES (?, ?, ?)
+			ON CONFLICT(keyword) DO UPDATE SET 
+				results_json = excluded.results_json,
+				cached_at = CURRENT_TIMESTAMP,
+				expires_at = excluded.expires_at
+		`, keyword, string(resultsJSON), expiresAt)
+		if err != nil {
+			return err
+		}
+		if err := upsertPlaces(tx, results); err != nil {
+			return err
+		}
+		return recordAppearances(tx, keyword, results)
+	})
 }
 
 // Delete removes a cache entry
@@ -80,3 +88,27 @@ func (r *CacheRepository) GetStats() (total int, valid int, err error) {
 	err = r.db.QueryRow("SELECT COUNT(*) FROM search_cache WHERE expires_at > datetime('now')").Scan(&valid)
 	return
 }
+
+// GetCachedPlaces returns every place in unexpired cache entries
+func (r *CacheRepository) GetCachedPlaces() ([]models.Place, error) {
+	rows, err := r.db.Query("SELECT results_json FROM search_cache WHERE expires_at > datetime('now')")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var places []models.Place
+	for rows.Next() {
+		var resultsJSON string
+		if err := rows.Scan(&resultsJSON); err != nil {
+			return nil, err
+		}
+		var results []models.Place
+		if err := json.Unmarshal([]byte(resultsJSON), &results); err != nil {
+			// Skip entries that were stored in an older format
+			continue
+		}
+		places = append(places, results...)
+	}
+	return places, rows.Err()
+}
@@ -0,0 +1,17 @@
+package repository
+
+import "errors"
+
+var (
+	// ErrNotFound is returned when the requested row does not exist
+	ErrNotFound = errors.New("not found")
+
+	// ErrAlreadyExists is returned when inserting a row that already exists
+	ErrAlreadyExists = errors.New("already exists")
+
+	// ErrVersionConflict is returned when an update was based on a stale version
+	ErrVersionConflict = errors.New("version conflict")
+
+	// ErrInvalidCursor is returned when a listing cursor cannot be decoded
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
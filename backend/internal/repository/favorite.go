@@ -2,27 +2,56 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/base64"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/jju-compass/jju-compass-map/internal/geo"
 	"github.com/jju-compass/jju-compass-map/internal/models"
+	"github.com/jju-compass/jju-compass-map/internal/textnorm"
 )
 
 // FavoriteRepository handles favorite place operations
 type FavoriteRepository struct {
-	db *sql.DB
+	db   querier
+	conn *sql.DB
 }
 
 // NewFavoriteRepository creates a new favorite repository
 func NewFavoriteRepository(db *sql.DB) *FavoriteRepository {
-	return &FavoriteRepository{db: db}
+	return &FavoriteRepository{db: db, conn: db}
+}
+
+// favoriteColumns is the column list read by scanFavorite
+const favoriteColumns = `id, user_id, place_id, place_name, address, road_address,
+	lat, lng, phone, category, created_at, updated_at, version`
+
+// scanFavorite reads a favorite selected with favoriteColumns
+func scanFavorite(s rowScanner) (models.Favorite, error) {
+	var f models.Favorite
+	var address, roadAddress, phone, category sql.NullString
+	err := s.Scan(&f.ID, &f.UserID, &f.PlaceID, &f.PlaceName,
+		&address, &roadAddress, &f.Lat, &f.Lng, &phone, &category,
+		&f.CreatedAt, &f.UpdatedAt, &f.Version)
+	if err != nil {
+		return f, err
+	}
+	f.Address = address.String
+	f.RoadAddress = roadAddress.String
+	f.Phone = phone.String
+	f.Category = category.String
+	return f, nil
 }
 
 // GetAll retrieves all favorites for a user
 func (r *FavoriteRepository) GetAll(userID string) ([]models.Favorite, error) {
 	rows, err := r.db.Query(`
-		SELECT id, user_id, place_id, place_name, address, road_address, 
-		       lat, lng, phone, category, created_at
-		FROM favorites 
-		WHERE user_id = ? 
+		SELECT `+favoriteColumns+`
+		FROM favorites
+		WHERE user_id = ? AND deleted_at IS NULL
 		ORDER BY created_at DESC
 	`, userID)
 	if err != nil {
@@ -32,51 +61,317 @@ func (r *FavoriteRepository) GetAll(userID string) ([]models.Favorite, error) {
 
 	var favorites []models.Favorite
 	for rows.Next() {
-		var f models.Favorite
-		var address, roadAddress, phone, category sql.NullString
-		err := rows.Scan(&f.ID, &f.UserID, &f.PlaceID, &f.PlaceName,
-			&address, &roadAddress, &f.Lat, &f.Lng, &phone, &category, &f.CreatedAt)
+		f, err := scanFavorite(rows)
 		if err != nil {
 			return nil, err
 		}
-		f.Address = address.String
-		f.RoadAddress = roadAddress.String
-		f.Phone = phone.String
-		f.Category = category.String
 		favorites = append(favorites, f)
 	}
 	return favorites, rows.Err()
 }
 
-// Add adds a new favorite
+// FavoriteListOptions filters and pages a favorites listing
+type FavoriteListOptions struct {
+	Near     *geo.Point // sort by distance from this point
+	Within   float64    // max distance in meters, requires Near (0 = unlimited)
+	Category string     // textnorm.Contains match on category
+	Query    string     // textnorm.Contains match on name and address
+	Cursor   string     // opaque cursor from a previous page
+	Limit    int        // page size (0 = unlimited)
+}
+
+// List retrieves favorites for a user with filtering, distance sorting and
+// cursor pagination. It returns the page and the cursor for the next page,
+// which is empty when there are no more results.
+func (r *FavoriteRepository) List(userID string, opts FavoriteListOptions) ([]models.Favorite, string, error) {
+	rows, err := r.db.Query(`
+		SELECT `+favoriteColumns+` FROM favorites WHERE user_id = ? AND deleted_at IS NULL
+	`, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	// Favorites per user are few, so text matching, distance filtering
+	// and ordering happen in Go rather than in SQL.
+	var favorites []models.Favorite
+	for rows.Next() {
+		f, err := scanFavorite(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		if opts.Category != "" && !textnorm.Contains(f.Category, opts.Category) {
+			continue
+		}
+		if opts.Query != "" && !textnorm.Contains(f.PlaceName, opts.Query) &&
+			!textnorm.Contains(f.Address, opts.Query) && !textnorm.Contains(f.RoadAddress, opts.Query) {
+			continue
+		}
+		if opts.Near != nil {
+			d := math.Round(geo.Distance(*opts.Near, geo.Point{Lat: f.Lat, Lng: f.Lng})*10) / 10
+			if opts.Within > 0 && d > opts.Within {
+				continue
+			}
+			f.Distance = &d
+		}
+		favorites = append(favorites, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	key := func(f *models.Favorite) float64 {
+		if f.Distance != nil {
+			return *f.Distance
+		}
+		// Newest first
+		return -float64(f.CreatedAt.UnixNano())
+	}
+	sort.Slice(favorites, func(i, j int) bool {
+		ki, kj := key(&favorites[i]), key(&favorites[j])
+		if ki != kj {
+			return ki < kj
+		}
+		return favorites[i].ID < favorites[j].ID
+	})
+
+	if opts.Cursor != "" {
+		afterKey, afterID, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		start := sort.Search(len(favorites), func(i int) bool {
+			k := key(&favorites[i])
+			return k > afterKey || (k == afterKey && favorites[i].ID > afterID)
+		})
+		favorites = favorites[start:]
+	}
+
+	var next string
+	if opts.Limit > 0 && len(favorites) > opts.Limit {
+		favorites = favorites[:opts.Limit]
+		last := &favorites[len(favorites)-1]
+		next = encodeCursor(key(last), last.ID)
+	}
+	return favorites, next, nil
+}
+
+// encodeCursor encodes a keyset position as an opaque string
+func encodeCursor(key float64, id int64) string {
+	raw := strconv.FormatFloat(key, 'g', -1, 64) + "|" + strconv.FormatInt(id, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor decodes a cursor produced by encodeCursor
+func decodeCursor(cursor string) (float64, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return 0, 0, ErrInvalidCursor
+	}
+	key, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return 0, 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, ErrInvalidCursor
+	}
+	return key, id, nil
+}
+
+// Get retrieves a single favorite, or nil if it does not exist
+func (r *FavoriteRepository) Get(userID, placeID string) (*models.Favorite, error) {
+	f, err := scanFavorite(r.db.QueryRow(`
+		SELECT `+favoriteColumns+`
+		FROM favorites
+		WHERE user_id = ? AND place_id = ? AND deleted_at IS NULL
+	`, userID, placeID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// Add adds a new favorite and records the place in the place catalog.
+// A soft-deleted favorite for the same place is replaced; a live one
+// results in ErrAlreadyExists.
 func (r *FavoriteRepository) Add(f *models.Favorite) error {
+	if r.db == querier(r.conn) {
+		return withTx(r.conn, func(tx *sql.Tx) error {
+			return (&FavoriteRepository{db: tx, conn: r.conn}).Add(f)
+		})
+	}
+
 	result, err := r.db.Exec(`
-		INSERT INTO favorites (user_id, place_id, place_name, address, road_address, lat, lng, phone, category)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO favorites (user_id, place_id, place_name, address, road_address, lat, lng, phone, category, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id, place_id) DO UPDATE SET
+			place_name = excluded.place_name,
+			address = excluded.address,
+			road_address = excluded.road_address,
+			lat = excluded.lat,
+			lng = excluded.lng,
+			phone = excluded.phone,
+			category = excluded.category,
+			created_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP,
+			version = favorites.version + 1,
+			deleted_at = NULL
+		WHERE favorites.deleted_at IS NOT NULL
 	`, f.UserID, f.PlaceID, f.PlaceName, f.Address, f.RoadAddress, f.Lat, f.Lng, f.Phone, f.Category)
 	if err != nil {
 		return err
 	}
 
-	id, err := result.LastInsertId()
+	affected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
-	f.ID = id
-	return nil
+	if affected == 0 {
+		return ErrAlreadyExists
+	}
+
+	// LastInsertId is not reliable when the upsert revived a tombstone
+	err = r.db.QueryRow(`
+		SELECT id, version FROM favorites WHERE user_id = ? AND place_id = ?
+	`, f.UserID, f.PlaceID).Scan(&f.ID, &f.Version)
+	if err != nil {
+		return err
+	}
+	return upsertFavoritePlace(r.db, f)
+}
+
+// Update applies a partial update to a favorite.
+// The update only succeeds if the stored version equals expectedVersion;
+// otherwise ErrVersionConflict is returned together with the current row.
+// ErrNotFound is returned if the favorite does not exist.
+func (r *FavoriteRepository) Update(userID, placeID string, patch *models.FavoritePatch, expectedVersion int64) (*models.Favorite, error) {
+	// The row read back must be the one this update wrote, since its
+	// version becomes the client's ETag
+	if r.db == querier(r.conn) {
+		var current *models.Favorite
+		conflict := false
+		err := withTx(r.conn, func(tx *sql.Tx) error {
+			var err error
+			current, err = (&FavoriteRepository{db: tx, conn: r.conn}).Update(userID, placeID, patch, expectedVersion)
+			if err == ErrVersionConflict {
+				conflict = true
+				return nil
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if conflict {
+			return current, ErrVersionConflict
+		}
+		return current, nil
+	}
+
+	result, err := r.db.Exec(`
+		UPDATE favorites SET
+			place_name = COALESCE(?, place_name),
+			address = COALESCE(?, address),
+			road_address = COALESCE(?, road_address),
+			lat = COALESCE(?, lat),
+			lng = COALESCE(?, lng),
+			phone = COALESCE(?, phone),
+			category = COALESCE(?, category),
+			updated_at = CURRENT_TIMESTAMP,
+			version = version + 1
+		WHERE user_id = ? AND place_id = ? AND version = ? AND deleted_at IS NULL
+	`, patch.PlaceName, patch.Address, patch.RoadAddress, patch.Lat, patch.Lng,
+		patch.Phone, patch.Category, userID, placeID, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := r.Get(userID, placeID)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, ErrNotFound
+	}
+	if affected == 0 {
+		return current, ErrVersionConflict
+	}
+	return current, nil
 }
 
-// Delete removes a favorite by user and place ID
+// Delete soft-deletes a favorite by user and place ID.
+// The row is kept until PurgeDeleted so that it can be restored.
+// ErrNotFound is returned if there was no favorite to delete.
 func (r *FavoriteRepository) Delete(userID, placeID string) error {
-	_, err := r.db.Exec("DELETE FROM favorites WHERE user_id = ? AND place_id = ?", userID, placeID)
-	return err
+	result, err := r.db.Exec(`
+		UPDATE favorites SET deleted_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND place_id = ? AND deleted_at IS NULL
+	`, userID, placeID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Restore undoes soft deletes made at or after since.
+// With a place ID only that favorite is restored; otherwise the most
+// recent deletion is restored. It returns the number of restored rows.
+func (r *FavoriteRepository) Restore(userID, placeID string, since time.Time) (int64, error) {
+	var result sql.Result
+	var err error
+	if placeID != "" {
+		result, err = r.db.Exec(`
+			UPDATE favorites SET deleted_at = NULL, version = version + 1, updated_at = CURRENT_TIMESTAMP
+			WHERE user_id = ? AND place_id = ? AND deleted_at >= ?
+		`, userID, placeID, sqlTime(since))
+	} else {
+		result, err = r.db.Exec(`
+			UPDATE favorites SET deleted_at = NULL, version = version + 1, updated_at = CURRENT_TIMESTAMP
+			WHERE user_id = ? AND deleted_at >= ? AND deleted_at = (
+				SELECT MAX(deleted_at) FROM favorites WHERE user_id = ?
+			)
+		`, userID, sqlTime(since), userID)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// PurgeDeleted permanently removes favorites soft-deleted before the given time
+func (r *FavoriteRepository) PurgeDeleted(before time.Time) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM favorites WHERE deleted_at < ?", sqlTime(before))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 // Exists checks if a place is in user's favorites
 func (r *FavoriteRepository) Exists(userID, placeID string) (bool, error) {
 	var count int
 	err := r.db.QueryRow(`
-		SELECT COUNT(*) FROM favorites WHERE user_id = ? AND place_id = ?
+		SELECT COUNT(*) FROM favorites WHERE user_id = ? AND place_id = ? AND deleted_at IS NULL
 	`, userID, placeID).Scan(&count)
 	return count > 0, err
 }
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jju-compass/jju-compass-map/internal/models"
+)
+
+// Bulk operation result statuses
+const (
+	BulkStatusOK         = "ok"
+	BulkStatusFailed     = "failed"
+	BulkStatusRolledBack = "rolled_back"
+	BulkStatusSkipped    = "skipped"
+)
+
+// Bulk applies favorite operations for a user in a single transaction.
+// In atomic mode the first failing operation rolls back the whole batch
+// and committed is false. Otherwise each operation runs in its own
+// savepoint, failures are reported per operation and the rest is committed.
+func (r *FavoriteRepository) Bulk(userID string, ops []models.FavoriteOperation, atomic bool) (results []models.FavoriteOperationResult, committed bool, err error) {
+	results = make([]models.FavoriteOperationResult, len(ops))
+	for i, op := range ops {
+		results[i] = models.FavoriteOperationResult{Index: i, Op: op.Op, PlaceID: op.PlaceID}
+	}
+
+	errBatchFailed := errors.New("batch failed")
+	err = withTx(r.conn, func(tx *sql.Tx) error {
+		txRepo := &FavoriteRepository{db: tx, conn: r.conn}
+		for i := range ops {
+			if !atomic {
+				if _, err := tx.Exec("SAVEPOINT bulk_op"); err != nil {
+					return err
+				}
+			}
+
+			favorite, opErr := txRepo.apply(userID, &ops[i])
+			if opErr != nil {
+				results[i].Status = BulkStatusFailed
+				results[i].Error = bulkErrorMessage(opErr)
+				if atomic {
+					for j := 0; j < i; j++ {
+						results[j].Status = BulkStatusRolledBack
+						results[j].Favorite = nil
+					}
+					for j := i + 1; j < len(results); j++ {
+						results[j].Status = BulkStatusSkipped
+					}
+					return errBatchFailed
+				}
+				if _, err := tx.Exec("ROLLBACK TO bulk_op"); err != nil {
+					return err
+				}
+			} else {
+				results[i].Status = BulkStatusOK
+				results[i].Favorite = favorite
+			}
+
+			if !atomic {
+				if _, err := tx.Exec("RELEASE bulk_op"); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+
+	if errors.Is(err, errBatchFailed) {
+		return results, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return results, true, nil
+}
+
+// apply runs a single bulk operation
+func (r *FavoriteRepository) apply(userID string, op *models.FavoriteOperation) (*models.Favorite, error) {
+	switch op.Op {
+	case models.FavoriteOpAdd:
+		if op.PlaceName == nil || op.Lat == nil || op.Lng == nil {
+			return nil, fmt.Errorf("place_name, lat and lng are required")
+		}
+		f := &models.Favorite{
+			UserID:    userID,
+			PlaceID:   op.PlaceID,
+			PlaceName: *op.PlaceName,
+			Lat:       *op.Lat,
+			Lng:       *op.Lng,
+		}
+		if op.Address != nil {
+			f.Address = *op.Address
+		}
+		if op.RoadAddress != nil {
+			f.RoadAddress = *op.RoadAddress
+		}
+		if op.Phone != nil {
+			f.Phone = *op.Phone
+		}
+		if op.Category != nil {
+			f.Category = *op.Category
+		}
+		if err := r.Add(f); err != nil {
+			return nil, err
+		}
+		return r.Get(userID, op.PlaceID)
+
+	case models.FavoriteOpUpdate:
+		version := op.Version
+		if version == 0 {
+			current, err := r.Get(userID, op.PlaceID)
+			if err != nil {
+				return nil, err
+			}
+			if current == nil {
+				return nil, ErrNotFound
+			}
+			version = current.Version
+		}
+		return r.Update(userID, op.PlaceID, &op.FavoritePatch, version)
+
+	case models.FavoriteOpDelete:
+		return nil, r.Delete(userID, op.PlaceID)
+	}
+	return nil, fmt.Errorf("unknown op %q", op.Op)
+}
+
+// bulkErrorMessage converts an operation error into a client-facing message
+func bulkErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, ErrAlreadyExists):
+		return "already_exists"
+	case errors.Is(err, ErrNotFound):
+		return "not_found"
+	case errors.Is(err, ErrVersionConflict):
+		return "version_conflict"
+	}
+	return err.Error()
+}
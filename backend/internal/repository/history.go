@@ -2,11 +2,15 @@ package repository
 
 import (
 	"database/sql"
+	"time"
 
 	"github.com/jju-compass/jju-compass-map/internal/models"
+	"github.com/jju-compass/jju-compass-map/internal/textnorm"
 )
 
-// HistoryRepository handles search history operations
+// HistoryRepository handles search history operations.
+// search_history keeps one row per user and normalized keyword, while
+// every search is also appended to search_events for global statistics.
 type HistoryRepository struct {
 	db *sql.DB
 }
@@ -18,67 +22,340 @@ func NewHistoryRepository(db *sql.DB) *HistoryRepository {
 
 // GetRecent retrieves recent search history for a user
 func (r *HistoryRepository) GetRecent(userID string, limit int) ([]models.SearchHistory, error) {
-	rows, err := r.db.Query(`
-		SELECT id, user_id, keyword, result_count, searched_at
+	return r.List(userID, HistoryListOptions{Limit: limit})
+}
+
+// HistoryListOptions filters a history listing
+type HistoryListOptions struct {
+	Query string    // textnorm.Contains match on keyword
+	From  time.Time // last searched at or after (zero = unbounded)
+	To    time.Time // last searched before (zero = unbounded)
+	Limit int
+}
+
+// List retrieves a user's search history, most recently searched first
+func (r *HistoryRepository) List(userID string, opts HistoryListOptions) ([]models.SearchHistory, error) {
+	query := `
+		SELECT id, user_id, keyword, count, result_count, first_searched_at, last_searched_at
 		FROM search_history
-		WHERE user_id = ?
-		ORDER BY searched_at DESC
-		LIMIT ?
-	`, userID, limit)
+		WHERE user_id = ? AND deleted_at IS NULL`
+	args := []interface{}{userID}
+	rangeClause, rangeArgs := timeRange("last_searched_at", opts.From, opts.To)
+	query += rangeClause + " ORDER BY last_searched_at DESC"
+	args = append(args, rangeArgs...)
+	// Keyword matching happens in Go, so the limit can only be applied
+	// in SQL when there is no query
+	if opts.Query == "" {
+		query += " LIMIT ?"
+		args = append(args, opts.Limit)
+	}
+
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	var history []models.SearchHistory
-	for rows.Next() {
+	for rows.Next() && len(history) < opts.Limit {
 		var h models.SearchHistory
-		err := rows.Scan(&h.ID, &h.UserID, &h.Keyword, &h.ResultCount, &h.SearchedAt)
+		err := rows.Scan(&h.ID, &h.UserID, &h.Keyword, &h.Count, &h.ResultCount, &h.FirstSearchedAt, &h.LastSearchedAt)
 		if err != nil {
 			return nil, err
 		}
+		if opts.Query != "" && !textnorm.Contains(h.Keyword, opts.Query) {
+			continue
+		}
 		history = append(history, h)
 	}
 	return history, rows.Err()
 }
 
-// Add adds a new search history entry
+// prefixScanLimit bounds how many recent entries SearchPrefix examines
+const prefixScanLimit = 500
+
+// SearchPrefix retrieves the user's history entries that start with what
+// the user typed (see textnorm.MatchPrefix), most recent first
+func (r *HistoryRepository) SearchPrefix(userID, prefix string, limit int) ([]models.SearchHistory, error) {
+	if textnorm.Key(prefix) == "" {
+		return nil, nil
+	}
+
+	// Jamo and initial-consonant matching cannot be expressed in SQL, so
+	// the user's most recent entries are matched in Go
+	recent, err := r.GetRecent(userID, prefixScanLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []models.SearchHistory
+	for _, h := range recent {
+		if len(matches) >= limit {
+			break
+		}
+		if textnorm.MatchPrefix(h.Keyword, prefix) {
+			matches = append(matches, h)
+		}
+	}
+	return matches, nil
+}
+
+// Add records a search: the event is appended, the keyword's trend
+// bucket incremented, and the user's entry for the normalized keyword
+// created or bumped. Searching a keyword whose
+// entry was deleted starts a fresh entry.
 func (r *HistoryRepository) Add(userID, keyword string, resultCount int) error {
-	_, err := r.db.Exec(`
-		INSERT INTO search_history (user_id, keyword, result_count)
-		VALUES (?, ?, ?)
-	`, userID, keyword, resultCount)
-	return err
+	normalized := textnorm.Key(keyword)
+	if normalized == "" {
+		return nil
+	}
+
+	return withTx(r.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			INSERT INTO search_events (user_id, keyword, normalized_keyword, result_count)
+			VALUES (?, ?, ?, ?)
+		`, userID, keyword, normalized, resultCount)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO keyword_trend_buckets (normalized_keyword, bucket_start, keyword, count)
+			VALUES (?, ?, ?, 1)
+			ON CONFLICT(normalized_keyword, bucket_start) DO UPDATE SET
+				keyword = excluded.keyword,
+				count = count + 1
+		`, normalized, trendBucket(time.Now()), keyword)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO search_history (user_id, keyword, normalized_keyword, result_count, first_searched_at, last_searched_at)
+			VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			ON CONFLICT(user_id, normalized_keyword) DO UPDATE SET
+				keyword = excluded.keyword,
+				count = CASE WHEN deleted_at IS NULL THEN count + 1 ELSE 1 END,
+				result_count = excluded.result_count,
+				first_searched_at = CASE WHEN deleted_at IS NULL THEN first_searched_at ELSE excluded.first_searched_at END,
+				last_searched_at = excluded.last_searched_at,
+				deleted_at = NULL
+		`, userID, keyword, normalized, resultCount)
+		return err
+	})
+}
+
+// PopularOptions controls which keywords are published as popular
+type PopularOptions struct {
+	Limit   int
+	UserCap int                       // max searches counted per user and keyword (0 = no cap)
+	Exclude func(keyword string) bool // e.g. moderation.Rules.Blocked
+}
+
+// popularBatchFactor sets how many more rows than requested GetPopular
+// reads at a time, leaving room for rule-based exclusions
+const popularBatchFactor = 4
+
+// GetPopular retrieves the most searched keywords across all users,
+// counted from the event stream plus the totals rolled up from expired
+// events, and shown as most recently typed. Pinned keywords come first
+// and hidden ones are left out; each user contributes at most
+// opts.UserCap searches to a keyword's count.
+func (r *HistoryRepository) GetPopular(opts PopularOptions) ([]models.PopularKeyword, error) {
+	if opts.Limit <= 0 {
+		return nil, nil
+	}
+	batch := opts.Limit * popularBatchFactor
+
+	// Rule-based exclusions cannot be expressed in SQL, so rows are read
+	// in batches until enough are left after excluding
+	var popular []models.PopularKeyword
+	for offset := 0; len(popular) < opts.Limit; offset += batch {
+		page, err := r.popularPage(opts.UserCap, batch, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range page {
+			if len(popular) == opts.Limit {
+				break
+			}
+			if opts.Exclude != nil && opts.Exclude(p.Keyword) {
+				continue
+			}
+			popular = append(popular, p)
+		}
+		if len(page) < batch {
+			break
+		}
+	}
+	return popular, nil
 }
 
-// GetPopular retrieves most popular search keywords
-func (r *HistoryRepository) GetPopular(limit int) ([]models.PopularKeyword, error) {
+// popularPage reads one page of popular keyword candidates, ranked
+func (r *HistoryRepository) popularPage(userCap, limit, offset int) ([]models.PopularKeyword, error) {
 	rows, err := r.db.Query(`
-		SELECT keyword, COUNT(*) as count
-		FROM search_history
-		GROUP BY keyword
-		ORDER BY count DESC
-		LIMIT ?
-	`, limit)
+		WITH per_user AS (
+			SELECT normalized_keyword, COUNT(*) AS count, MAX(id) AS latest_id
+			FROM search_events
+			GROUP BY normalized_keyword, user_id
+		), live AS (
+			SELECT normalized_keyword,
+				SUM(CASE WHEN ? > 0 AND count > ? THEN ? ELSE count END) AS count,
+				MAX(latest_id) AS latest_id
+			FROM per_user
+			GROUP BY normalized_keyword
+		), ranked AS (
+			SELECT normalized_keyword, SUM(count) AS count, MAX(latest_id) AS latest_id
+			FROM (
+				SELECT normalized_keyword, count, latest_id FROM live
+				UNION ALL
+				SELECT normalized_keyword, count, NULL FROM search_keyword_totals
+			)
+			GROUP BY normalized_keyword
+		)
+		SELECT COALESCE(e.keyword, t.keyword), r.count, COALESCE(m.status = 'pinned', 0) AS pinned
+		FROM ranked r
+		LEFT JOIN search_events e ON e.id = r.latest_id
+		LEFT JOIN search_keyword_totals t ON t.normalized_keyword = r.normalized_keyword
+		LEFT JOIN keyword_moderation m ON m.normalized_keyword = r.normalized_keyword
+		WHERE m.status IS NULL OR m.status <> 'hidden'
+		UNION ALL
+		SELECT keyword, 0, 1 FROM keyword_moderation
+		WHERE status = 'pinned' AND normalized_keyword NOT IN (SELECT normalized_keyword FROM ranked)
+		ORDER BY pinned DESC, 2 DESC, 1
+		LIMIT ? OFFSET ?
+	`, userCap, userCap, userCap, limit, offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var popular []models.PopularKeyword
+	var page []models.PopularKeyword
 	for rows.Next() {
 		var p models.PopularKeyword
-		err := rows.Scan(&p.Keyword, &p.Count)
-		if err != nil {
+		if err := rows.Scan(&p.Keyword, &p.Count, &p.Pinned); err != nil {
 			return nil, err
 		}
-		popular = append(popular, p)
+		page = append(page, p)
 	}
-	return popular, rows.Err()
+	return page, rows.Err()
+}
+
+// RollupEvents folds search events older than before into
+// search_keyword_totals and deletes them, keeping popular counts while
+// the event table stays bounded. userCap applies to the rolled-up events
+// as in GetPopular. It returns the number of events rolled up.
+func (r *HistoryRepository) RollupEvents(before time.Time, userCap int) (int64, error) {
+	var rolled int64
+	err := withTx(r.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			WITH per_user AS (
+				SELECT normalized_keyword,
+					CASE WHEN ? > 0 AND COUNT(*) > ? THEN ? ELSE COUNT(*) END AS count,
+					MAX(id) AS latest_id, MAX(searched_at) AS last_searched_at
+				FROM search_events
+				WHERE searched_at < ?
+				GROUP BY normalized_keyword, user_id
+			), rolled AS (
+				SELECT normalized_keyword, SUM(count) AS count,
+					MAX(latest_id) AS latest_id, MAX(last_searched_at) AS last_searched_at
+				FROM per_user
+				GROUP BY normalized_keyword
+			)
+			INSERT INTO search_keyword_totals (normalized_keyword, keyword, count, last_searched_at)
+			SELECT r.normalized_keyword, e.keyword, r.count, r.last_searched_at
+			FROM rolled r
+			JOIN search_events e ON e.id = r.latest_id
+			WHERE true
+			ON CONFLICT(normalized_keyword) DO UPDATE SET
+				keyword = excluded.keyword,
+				count = count + excluded.count,
+				last_searched_at = excluded.last_searched_at
+		`, userCap, userCap, userCap, sqlTime(before))
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.Exec("DELETE FROM search_events WHERE searched_at < ?", sqlTime(before))
+		if err != nil {
+			return err
+		}
+		rolled, err = result.RowsAffected()
+		return err
+	})
+	return rolled, err
 }
 
-// DeleteAll removes all search history for a user
+// DeleteAll soft-deletes all search history for a user.
+// The rows are kept until PurgeDeleted so that they can be restored.
 func (r *HistoryRepository) DeleteAll(userID string) error {
-	_, err := r.db.Exec("DELETE FROM search_history WHERE user_id = ?", userID)
+	_, err := r.db.Exec(`
+		UPDATE search_history SET deleted_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND deleted_at IS NULL
+	`, userID)
 	return err
 }
+
+// Delete soft-deletes a single history entry owned by the user.
+// ErrNotFound is returned if the user has no such entry.
+func (r *HistoryRepository) Delete(userID string, id int64) error {
+	result, err := r.db.Exec(`
+		UPDATE search_history SET deleted_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND user_id = ? AND deleted_at IS NULL
+	`, id, userID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteKeyword soft-deletes the user's entry for a keyword, matched by
+// its normalized form. ErrNotFound is returned if there is none.
+func (r *HistoryRepository) DeleteKeyword(userID, keyword string) error {
+	result, err := r.db.Exec(`
+		UPDATE search_history SET deleted_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND normalized_keyword = ? AND deleted_at IS NULL
+	`, userID, textnorm.Key(keyword))
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Restore undoes the most recent deletion if it happened at or after since.
+// It returns the number of restored rows.
+func (r *HistoryRepository) Restore(userID string, since time.Time) (int64, error) {
+	result, err := r.db.Exec(`
+		UPDATE search_history SET deleted_at = NULL
+		WHERE user_id = ? AND deleted_at >= ? AND deleted_at = (
+			SELECT MAX(deleted_at) FROM search_history WHERE user_id = ?
+		)
+	`, userID, sqlTime(since), userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// PurgeDeleted permanently removes history soft-deleted before the given time
+func (r *HistoryRepository) PurgeDeleted(before time.Time) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM search_history WHERE deleted_at < ?", sqlTime(before))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jju-compass/jju-compass-map/internal/models"
+)
+
+// mergeFavorite is the part of a favorite compared when both identities
+// saved the same place
+type mergeFavorite struct {
+	id                                    int64
+	placeName                             string
+	address, roadAddress, phone, category sql.NullString
+	lat, lng                              float64
+	createdAt, updatedAt                  time.Time
+	deleted                               bool
+}
+
+// richness scores how much a favorite is worth keeping: a live record
+// beats a deleted one, then the one with more details filled in wins
+func (f *mergeFavorite) richness() int {
+	score := 0
+	if !f.deleted {
+		score += 10
+	}
+	for _, s := range []sql.NullString{f.address, f.roadAddress, f.phone, f.category} {
+		if s.String != "" {
+			score++
+		}
+	}
+	return score
+}
+
+// MergeAnonymous moves everything stored under an anonymous identity to
+// an account in one transaction. Favorites of a place both identities
+// saved keep the richer record, and history entries of the same keyword
+// are combined. The anonymous rows are gone afterwards, so merging again
+// is a no-op that returns an empty summary.
+func (r *UserRepository) MergeAnonymous(from, to string) (*models.MergeSummary, error) {
+	var summary models.MergeSummary
+	if from == "" || from == to {
+		return &summary, nil
+	}
+
+	err := withTx(r.db, func(tx *sql.Tx) error {
+		var err error
+		if summary.Favorites, summary.FavoritesMerged, err = mergeFavorites(tx, from, to); err != nil {
+			return err
+		}
+		if summary.History, summary.HistoryMerged, err = mergeHistory(tx, from, to); err != nil {
+			return err
+		}
+		if summary.SearchEvents, err = reassign(tx, "search_events", "user_id", from, to); err != nil {
+			return err
+		}
+		if summary.Visits, err = reassign(tx, "visits", "user_id", from, to); err != nil {
+			return err
+		}
+		summary.SharedLists, err = reassign(tx, "shared_lists", "owner_id", from, to)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// reassign moves the rows of a table without per-user uniqueness
+func reassign(tx *sql.Tx, table, column, from, to string) (int, error) {
+	result, err := tx.Exec("UPDATE "+table+" SET "+column+" = ? WHERE "+column+" = ?", to, from)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// loadMergeFavorites reads the favorites of userID, including deleted
+// ones, keyed by place ID
+func loadMergeFavorites(tx *sql.Tx, userID string) (map[string]*mergeFavorite, error) {
+	rows, err := tx.Query(`
+		SELECT id, place_id, place_name, address, road_address, phone, category,
+			lat, lng, created_at, updated_at, deleted_at IS NOT NULL
+		FROM favorites
+		WHERE user_id = ?
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	favorites := make(map[string]*mergeFavorite)
+	for rows.Next() {
+		var f mergeFavorite
+		var placeID string
+		var updatedAt sql.NullTime
+		err := rows.Scan(&f.id, &placeID, &f.placeName, &f.address, &f.roadAddress, &f.phone, &f.category,
+			&f.lat, &f.lng, &f.createdAt, &updatedAt, &f.deleted)
+		if err != nil {
+			return nil, err
+		}
+		f.updatedAt = updatedAt.Time
+		favorites[placeID] = &f
+	}
+	return favorites, rows.Err()
+}
+
+// mergeFavorites moves favorites to the account. When the account already
+// has the place, the richer record is kept in the account's row so that
+// its ID and version history carry on.
+func mergeFavorites(tx *sql.Tx, from, to string) (moved, merged int, err error) {
+	source, err := loadMergeFavorites(tx, from)
+	if err != nil || len(source) == 0 {
+		return 0, 0, err
+	}
+	target, err := loadMergeFavorites(tx, to)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for placeID, src := range source {
+		dst, ok := target[placeID]
+		if !ok {
+			if _, err := tx.Exec("UPDATE favorites SET user_id = ? WHERE id = ?", to, src.id); err != nil {
+				return 0, 0, err
+			}
+			moved++
+			continue
+		}
+
+		createdAt := dst.createdAt
+		if src.createdAt.Before(createdAt) {
+			createdAt = src.createdAt
+		}
+		if src.richness() > dst.richness() ||
+			(src.richness() == dst.richness() && src.updatedAt.After(dst.updatedAt)) {
+			var deletedAt interface{}
+			if src.deleted {
+				deletedAt = sqlTime(time.Now())
+			}
+			_, err := tx.Exec(`
+				UPDATE favorites SET
+					place_name = ?, address = ?, road_address = ?, phone = ?, category = ?,
+					lat = ?, lng = ?, created_at = ?, deleted_at = ?,
+					updated_at = CURRENT_TIMESTAMP, version = version + 1
+				WHERE id = ?
+			`, src.placeName, src.address, src.roadAddress, src.phone, src.category,
+				src.lat, src.lng, sqlTime(createdAt), deletedAt, dst.id)
+			if err != nil {
+				return 0, 0, err
+			}
+		} else if createdAt.Before(dst.createdAt) {
+			if _, err := tx.Exec("UPDATE favorites SET created_at = ? WHERE id = ?", sqlTime(createdAt), dst.id); err != nil {
+				return 0, 0, err
+			}
+		}
+		if _, err := tx.Exec("DELETE FROM favorites WHERE id = ?", src.id); err != nil {
+			return 0, 0, err
+		}
+		merged++
+	}
+
+	placeIDs := make([]string, 0, len(source))
+	for placeID := range source {
+		placeIDs = append(placeIDs, placeID)
+	}
+	return moved, merged, catalogFavorites(tx, to, placeIDs)
+}
+
+// mergeHistory moves search history to the account. Entries for a keyword
+// the account also searched are combined: counts add up and the later
+// search supplies the spelling and result count. A deleted entry never
+// outweighs a live one.
+func mergeHistory(tx *sql.Tx, from, to string) (moved, merged int, err error) {
+	rows, err := tx.Query(`
+		SELECT s.id, t.id, s.keyword, s.count, s.result_count, s.first_searched_at, s.last_searched_at,
+			s.deleted_at IS NOT NULL, t.deleted_at IS NOT NULL
+		FROM search_history s
+		JOIN search_history t ON t.user_id = ? AND t.normalized_keyword = s.normalized_keyword
+		WHERE s.user_id = ?
+	`, to, from)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	type conflict struct {
+		srcID, dstID                int64
+		keyword                     string
+		count, resultCount          int
+		firstSearched, lastSearched time.Time
+		srcDeleted, dstDeleted      bool
+	}
+	var conflicts []conflict
+	for rows.Next() {
+		var c conflict
+		err := rows.Scan(&c.srcID, &c.dstID, &c.keyword, &c.count, &c.resultCount,
+			&c.firstSearched, &c.lastSearched, &c.srcDeleted, &c.dstDeleted)
+		if err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		conflicts = append(conflicts, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	for _, c := range conflicts {
+		switch {
+		case c.srcDeleted:
+			// The account's entry stands
+		case c.dstDeleted:
+			_, err = tx.Exec(`
+				UPDATE search_history SET
+					keyword = ?, count = ?, result_count = ?,
+					first_searched_at = ?, last_searched_at = ?, deleted_at = NULL
+				WHERE id = ?
+			`, c.keyword, c.count, c.resultCount, sqlTime(c.firstSearched), sqlTime(c.lastSearched), c.dstID)
+		default:
+			_, err = tx.Exec(`
+				UPDATE search_history SET
+					keyword = CASE WHEN last_searched_at < ? THEN ? ELSE keyword END,
+					result_count = CASE WHEN last_searched_at < ? THEN ? ELSE result_count END,
+					count = count + ?,
+					first_searched_at = MIN(first_searched_at, ?),
+					last_searched_at = MAX(last_searched_at, ?)
+				WHERE id = ?
+			`, sqlTime(c.lastSearched), c.keyword, sqlTime(c.lastSearched), c.resultCount, c.count,
+				sqlTime(c.firstSearched), sqlTime(c.lastSearched), c.dstID)
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		if _, err := tx.Exec("DELETE FROM search_history WHERE id = ?", c.srcID); err != nil {
+			return 0, 0, err
+		}
+		merged++
+	}
+
+	moved, err = reassign(tx, "search_history", "user_id", from, to)
+	return moved, merged, err
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/jju-compass/jju-compass-map/internal/models"
+	"github.com/jju-compass/jju-compass-map/internal/textnorm"
+)
+
+// ModerationRepository handles admin decisions about public keywords
+type ModerationRepository struct {
+	db *sql.DB
+}
+
+// NewModerationRepository creates a new moderation repository
+func NewModerationRepository(db *sql.DB) *ModerationRepository {
+	return &ModerationRepository{db: db}
+}
+
+// List returns all moderated keywords, most recently changed first
+func (r *ModerationRepository) List() ([]models.KeywordModeration, error) {
+	rows, err := r.db.Query(`
+		SELECT keyword, normalized_keyword, status, note, created_at, updated_at
+		FROM keyword_moderation
+		ORDER BY updated_at DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []models.KeywordModeration
+	for rows.Next() {
+		var m models.KeywordModeration
+		var note sql.NullString
+		err := rows.Scan(&m.Keyword, &m.NormalizedKeyword, &m.Status, &note, &m.CreatedAt, &m.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		m.Note = note.String
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+// Set hides or pins a keyword, matched by its normalized form
+func (r *ModerationRepository) Set(keyword, status, note string) (*models.KeywordModeration, error) {
+	normalized := textnorm.Key(keyword)
+	_, err := r.db.Exec(`
+		INSERT INTO keyword_moderation (normalized_keyword, keyword, status, note)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(normalized_keyword) DO UPDATE SET
+			keyword = excluded.keyword,
+			status = excluded.status,
+			note = excluded.note,
+			updated_at = CURRENT_TIMESTAMP
+	`, normalized, keyword, status, note)
+	if err != nil {
+		return nil, err
+	}
+	return r.Get(keyword)
+}
+
+// Get returns the moderation of a keyword, or nil if it is not moderated
+func (r *ModerationRepository) Get(keyword string) (*models.KeywordModeration, error) {
+	var m models.KeywordModeration
+	var note sql.NullString
+	err := r.db.QueryRow(`
+		SELECT keyword, normalized_keyword, status, note, created_at, updated_at
+		FROM keyword_moderation WHERE normalized_keyword = ?
+	`, textnorm.Key(keyword)).Scan(&m.Keyword, &m.NormalizedKeyword, &m.Status, &note, &m.CreatedAt, &m.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.Note = note.String
+	return &m, nil
+}
+
+// Delete removes the moderation of a keyword.
+// ErrNotFound is returned if the keyword was not moderated.
+func (r *ModerationRepository) Delete(keyword string) error {
+	result, err := r.db.Exec("DELETE FROM keyword_moderation WHERE normalized_keyword = ?", textnorm.Key(keyword))
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// IsHidden reports whether a keyword is hidden
+func (r *ModerationRepository) IsHidden(keyword string) (bool, error) {
+	var count int
+	err := r.db.QueryRow(`
+		SELECT COUNT(*) FROM keyword_moderation WHERE normalized_keyword = ? AND status = 'hidden'
+	`, textnorm.Key(keyword)).Scan(&count)
+	return count > 0, err
+}
@@ -0,0 +1,348 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/jju-compass/jju-compass-map/internal/models"
+)
+
+// erasedIDPrefix marks the pseudonyms that replace a deleted user's ID in
+// aggregate data
+const erasedIDPrefix = "erased_"
+
+// personalTables lists every table holding data about a user, in the
+// order erasure runs, with the query Export reads it with and the
+// statement that erases it. Both take the subject, or the account ID when
+// account is set. Anonymized tables are rewritten to a pseudonym, passed
+// before the subject, instead of deleted.
+var personalTables = []struct {
+	table      string
+	account    bool
+	anonymized bool
+	export     string
+	erase      string
+}{
+	{
+		table:      "search_events",
+		anonymized: true,
+		export:     "SELECT * FROM search_events WHERE user_id = ? ORDER BY id",
+		erase:      "UPDATE search_events SET user_id = ? WHERE user_id = ?",
+	},
+	{
+		table: "shared_list_items",
+		export: `SELECT i.* FROM shared_list_items i
+			JOIN shared_lists l ON l.id = i.list_id
+			WHERE l.owner_id = ?
+			ORDER BY i.list_id, i.place_id`,
+		erase: "DELETE FROM shared_list_items WHERE list_id IN (SELECT id FROM shared_lists WHERE owner_id = ?)",
+	},
+	{
+		table:  "shared_lists",
+		export: "SELECT * FROM shared_lists WHERE owner_id = ? ORDER BY id",
+		erase:  "DELETE FROM shared_lists WHERE owner_id = ?",
+	},
+	{
+		table:  "favorites",
+		export: "SELECT * FROM favorites WHERE user_id = ? ORDER BY id",
+		erase:  "DELETE FROM favorites WHERE user_id = ?",
+	},
+	{
+		table:  "search_history",
+		export: "SELECT * FROM search_history WHERE user_id = ? ORDER BY id",
+		erase:  "DELETE FROM search_history WHERE user_id = ?",
+	},
+	{
+		table:  "visits",
+		export: "SELECT * FROM visits WHERE user_id = ? ORDER BY id",
+		erase:  "DELETE FROM visits WHERE user_id = ?",
+	},
+	{
+		// Audit entries must be kept, so only their link to the user goes
+		table: "audit_log",
+		export: `SELECT a.id, a.action, a.target, a.before_json, a.after_json, a.created_at
+			FROM audit_log a
+			JOIN audit_subjects s ON s.pseudonym = a.actor
+			WHERE s.subject = ?
+			ORDER BY a.id`,
+		erase: "DELETE FROM audit_subjects WHERE subject = ?",
+	},
+	{
+		// Session token hashes are left out of the export
+		table:   "sessions",
+		account: true,
+		export: `SELECT id, user_agent, created_at, last_seen_at, expires_at, revoked_at
+			FROM sessions WHERE user_id = ? ORDER BY id`,
+		erase: "DELETE FROM sessions WHERE user_id = ?",
+	},
+	{
+		table:   "users",
+		account: true,
+		export: `SELECT id, provider, provider_user_id, nickname, profile_image_url, created_at, last_login_at
+			FROM users WHERE id = ?`,
+		erase: "DELETE FROM users WHERE id = ?",
+	},
+}
+
+// PersonalDataRepository exports and erases everything stored about a user
+type PersonalDataRepository struct {
+	db *sql.DB
+}
+
+// NewPersonalDataRepository creates a new personal data repository
+func NewPersonalDataRepository(db *sql.DB) *PersonalDataRepository {
+	return &PersonalDataRepository{db: db}
+}
+
+// dumpRows reads a query's rows as column name to value maps
+func dumpRows(q querier, query string, args ...interface{}) ([]map[string]interface{}, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []map[string]interface{}{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			row[column] = values[i]
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+// Export returns every row stored under subject, keyed by table name.
+// accountID adds the account and its sessions when the user is logged
+// in (0 = anonymous).
+func (r *PersonalDataRepository) Export(subject string, accountID int64) (map[string][]map[string]interface{}, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() // read-only: a consistent snapshot across tables
+
+	data := make(map[string][]map[string]interface{})
+	for _, t := range personalTables {
+		var owner interface{} = subject
+		if t.account {
+			if accountID == 0 {
+				continue
+			}
+			owner = accountID
+		}
+		data[t.table], err = dumpRows(tx, t.export, owner)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// deletionColumns is the column list read by scanDeletionJob
+const deletionColumns = `id, status, deleted_rows, anonymized_rows, error,
+	requested_at, started_at, finished_at`
+
+// scanDeletionJob reads a job selected with deletionColumns
+func scanDeletionJob(s rowScanner) (models.DeletionJob, error) {
+	var j models.DeletionJob
+	var jobErr sql.NullString
+	var startedAt, finishedAt sql.NullTime
+	err := s.Scan(&j.ID, &j.Status, &j.DeletedRows, &j.AnonymizedRows, &jobErr,
+		&j.RequestedAt, &startedAt, &finishedAt)
+	if err != nil {
+		return j, err
+	}
+	j.Error = jobErr.String
+	if startedAt.Valid {
+		j.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		j.FinishedAt = &finishedAt.Time
+	}
+	return j, nil
+}
+
+// RequestDeletion queues the erasure of subject's data. A request that is
+// already queued or running is returned instead of adding another.
+func (r *PersonalDataRepository) RequestDeletion(subject string, accountID int64) (*models.DeletionJob, error) {
+	hash := hashToken(subject)
+
+	job, err := scanDeletionJob(r.db.QueryRow(`
+		SELECT `+deletionColumns+` FROM deletion_jobs
+		WHERE subject_hash = ? AND status IN (?, ?)
+	`, hash, models.DeletionPending, models.DeletionRunning))
+	if err == nil {
+		return &job, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	id, err := newSlug()
+	if err != nil {
+		return nil, err
+	}
+	var account interface{}
+	if accountID != 0 {
+		account = accountID
+	}
+	_, err = r.db.Exec(`
+		INSERT INTO deletion_jobs (id, subject, account_id, subject_hash, status)
+		VALUES (?, ?, ?, ?, ?)
+	`, id, subject, account, hash, models.DeletionPending)
+	if err != nil {
+		return nil, err
+	}
+	return r.GetDeletion(id)
+}
+
+// GetDeletion retrieves a deletion job, or nil if it does not exist
+func (r *PersonalDataRepository) GetDeletion(id string) (*models.DeletionJob, error) {
+	job, err := scanDeletionJob(r.db.QueryRow(`SELECT `+deletionColumns+` FROM deletion_jobs WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// PendingDeletions returns the IDs of queued deletion jobs, oldest first
+func (r *PersonalDataRepository) PendingDeletions() ([]string, error) {
+	rows, err := r.db.Query(`
+		SELECT id FROM deletion_jobs WHERE status = ? ORDER BY requested_at
+	`, models.DeletionPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// RequeueInterrupted puts jobs left running by a server that stopped
+// mid-deletion back in the queue. Their transaction never committed, so
+// they can safely run again. Call it at startup.
+func (r *PersonalDataRepository) RequeueInterrupted() (int64, error) {
+	result, err := r.db.Exec(`
+		UPDATE deletion_jobs SET status = ?, started_at = NULL WHERE status = ?
+	`, models.DeletionPending, models.DeletionRunning)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// RunDeletion claims a queued job and erases its subject's data in one
+// transaction. Personal rows are deleted; search events, which feed
+// popular keywords, are kept under a random pseudonym, and audit entries
+// lose the mapping that named the user. Once done, the
+// job keeps only a hash of the user ID. It reports false if the job was
+// not pending, e.g. because another worker claimed it.
+func (r *PersonalDataRepository) RunDeletion(id string) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE deletion_jobs SET status = ?, started_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND status = ?
+	`, models.DeletionRunning, id, models.DeletionPending)
+	if err != nil {
+		return false, err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	err = withTx(r.db, func(tx *sql.Tx) error {
+		var subject string
+		var accountID sql.NullInt64
+		err := tx.QueryRow("SELECT subject, account_id FROM deletion_jobs WHERE id = ?", id).
+			Scan(&subject, &accountID)
+		if err != nil {
+			return err
+		}
+		deleted, anonymized, err := erasePersonalData(tx, subject, accountID.Int64)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`
+			UPDATE deletion_jobs SET
+				status = ?, deleted_rows = ?, anonymized_rows = ?,
+				subject = NULL, account_id = NULL, finished_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, models.DeletionDone, deleted, anonymized, id)
+		return err
+	})
+	if err != nil {
+		_, markErr := r.db.Exec(`
+			UPDATE deletion_jobs SET status = ?, error = ?, finished_at = CURRENT_TIMESTAMP
+			WHERE id = ?
+		`, models.DeletionFailed, err.Error(), id)
+		if markErr != nil {
+			return true, markErr
+		}
+		return true, err
+	}
+	return true, nil
+}
+
+// erasePersonalData removes everything stored under subject and the
+// account, returning how many rows were deleted and anonymized
+func erasePersonalData(tx *sql.Tx, subject string, accountID int64) (deleted, anonymized int64, err error) {
+	pseudonym, err := newSlug()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, t := range personalTables {
+		args := []interface{}{subject}
+		if t.account {
+			if accountID == 0 {
+				continue
+			}
+			args[0] = accountID
+		}
+		if t.anonymized {
+			args = append([]interface{}{erasedIDPrefix + pseudonym}, args...)
+		}
+
+		result, err := tx.Exec(t.erase, args...)
+		if err != nil {
+			return 0, 0, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, 0, err
+		}
+		if t.anonymized {
+			anonymized += n
+		} else {
+			deleted += n
+		}
+	}
+	return deleted, anonymized, nil
+}
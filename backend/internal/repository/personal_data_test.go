@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jju-compass/jju-compass-map/internal/database"
+	"github.com/jju-compass/jju-compass-map/internal/models"
+)
+
+// openTestDB opens a migrated database in a temporary directory
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	if err := database.Connect(filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := database.InitSchema(); err != nil {
+		t.Fatal(err)
+	}
+	return database.DB
+}
+
+// seedPersonalData stores a row in every personal table for subject and
+// its account
+func seedPersonalData(t *testing.T, db *sql.DB, subject string, accountID int64) {
+	t.Helper()
+	now := sqlTime(time.Now())
+	stmts := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`INSERT INTO favorites (user_id, place_id, place_name, lat, lng, updated_at) VALUES (?, 'p1', '카페', 35.8, 127.1, ?)`,
+			[]interface{}{subject, now}},
+		{`INSERT INTO search_history (user_id, keyword, normalized_keyword, first_searched_at, last_searched_at) VALUES (?, '카페', '카페', ?, ?)`,
+			[]interface{}{subject, now, now}},
+		{`INSERT INTO search_events (user_id, keyword, normalized_keyword, searched_at) VALUES (?, '카페', '카페', ?)`,
+			[]interface{}{subject, now}},
+		{`INSERT INTO visits (user_id, place_id, place_name, visited_at) VALUES (?, 'p1', '카페', ?)`,
+			[]interface{}{subject, now}},
+		{`INSERT INTO shared_lists (owner_id, slug, title) VALUES (?, ?, '목록')`,
+			[]interface{}{subject, "slug-" + subject}},
+		{`INSERT INTO shared_list_items (list_id, place_id) SELECT id, 'p1' FROM shared_lists WHERE owner_id = ?`,
+			[]interface{}{subject}},
+		{`INSERT INTO sessions (token_hash, user_id, expires_at) VALUES (?, ?, ?)`,
+			[]interface{}{"hash-" + subject, accountID, sqlTime(time.Now().Add(time.Hour))}},
+	}
+	for _, s := range stmts {
+		if _, err := db.Exec(s.query, s.args...); err != nil {
+			t.Fatalf("seed %q: %v", s.query, err)
+		}
+	}
+
+	audit := NewAuditRepository(db)
+	actor, err := audit.Pseudonym(subject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := audit.Record(&models.AuditEntry{Actor: actor, Action: "favorite.delete", Target: "favorite:p1"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// exportCounts returns how many rows Export finds in each table
+func exportCounts(t *testing.T, repo *PersonalDataRepository, subject string, accountID int64) map[string]int {
+	t.Helper()
+	data, err := repo.Export(subject, accountID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[string]int)
+	for table, rows := range data {
+		counts[table] = len(rows)
+	}
+	return counts
+}
+
+func TestRunDeletionErasesEveryPersonalTable(t *testing.T) {
+	db := openTestDB(t)
+	repo := NewPersonalDataRepository(db)
+	users := NewUserRepository(db)
+
+	var accounts [2]int64
+	var subjects [2]string
+	for i := range accounts {
+		user, err := users.Login("kakao", fmt.Sprint(1000+i), "닉네임", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		accounts[i] = user.ID
+		subjects[i] = fmt.Sprintf("user_%d", user.ID)
+		seedPersonalData(t, db, subjects[i], accounts[i])
+	}
+	erased, kept := subjects[0], subjects[1]
+
+	// Every table the erasure covers must have been seeded
+	for _, pt := range personalTables {
+		if n := exportCounts(t, repo, erased, accounts[0])[pt.table]; n == 0 {
+			t.Fatalf("%s: not seeded", pt.table)
+		}
+	}
+
+	job, err := repo.RequestDeletion(erased, accounts[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ran, err := repo.RunDeletion(job.ID)
+	if err != nil || !ran {
+		t.Fatalf("RunDeletion = %v, %v", ran, err)
+	}
+
+	job, err = repo.GetDeletion(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != models.DeletionDone {
+		t.Fatalf("status = %q, error %q", job.Status, job.Error)
+	}
+	if job.AnonymizedRows != 1 {
+		t.Errorf("anonymized rows = %d, want 1", job.AnonymizedRows)
+	}
+
+	for table, n := range exportCounts(t, repo, erased, accounts[0]) {
+		if n != 0 {
+			t.Errorf("%s: %d rows left", table, n)
+		}
+	}
+	for _, c := range []struct {
+		query string
+		args  []interface{}
+	}{
+		{"SELECT COUNT(*) FROM favorites WHERE user_id = ?", []interface{}{erased}},
+		{"SELECT COUNT(*) FROM search_history WHERE user_id = ?", []interface{}{erased}},
+		{"SELECT COUNT(*) FROM search_events WHERE user_id = ?", []interface{}{erased}},
+		{"SELECT COUNT(*) FROM visits WHERE user_id = ?", []interface{}{erased}},
+		{"SELECT COUNT(*) FROM shared_lists WHERE owner_id = ?", []interface{}{erased}},
+		{"SELECT COUNT(*) FROM audit_subjects WHERE subject = ?", []interface{}{erased}},
+		{"SELECT COUNT(*) FROM deletion_jobs WHERE subject = ?", []interface{}{erased}},
+		{"SELECT COUNT(*) FROM sessions WHERE user_id = ?", []interface{}{accounts[0]}},
+		{"SELECT COUNT(*) FROM users WHERE id = ?", []interface{}{accounts[0]}},
+		{"SELECT COUNT(*) FROM shared_list_items i LEFT JOIN shared_lists l ON l.id = i.list_id WHERE l.id IS NULL", nil},
+	} {
+		var n int
+		if err := db.QueryRow(c.query, c.args...).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 {
+			t.Errorf("%s %v = %d, want 0", c.query, c.args, n)
+		}
+	}
+
+	// The other user's data is untouched
+	for table, n := range exportCounts(t, repo, kept, accounts[1]) {
+		if n == 0 {
+			t.Errorf("%s: other user's rows were erased", table)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package repository
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/jju-compass/jju-compass-map/internal/models"
+	"github.com/jju-compass/jju-compass-map/internal/textnorm"
+)
+
+// PlaceRepository handles the normalized place corpus built from cached
+// search results
+type PlaceRepository struct {
+	db *sql.DB
+}
+
+// NewPlaceRepository creates a new place repository
+func NewPlaceRepository(db *sql.DB) *PlaceRepository {
+	return &PlaceRepository{db: db}
+}
+
+// upsertPlaces stores places seen in search results and refreshes their
+// full-text index entries. Search results are authoritative, so they
+// overwrite whatever the catalog held, including a favorite source.
+func upsertPlaces(q querier, places []models.Place) error {
+	for _, p := range places {
+		if p.ID == "" || p.PlaceName == "" {
+			continue
+		}
+		lat, _ := strconv.ParseFloat(p.Y, 64)
+		lng, _ := strconv.ParseFloat(p.X, 64)
+
+		var id int64
+		err := q.QueryRow(`
+			INSERT INTO places (place_id, place_name, category_name, category_group_code, category_group_name,
+				phone, address, road_address, lat, lng, place_url, source, first_seen, last_seen)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			ON CONFLICT(place_id) DO UPDATE SET
+				place_name = excluded.place_name,
+				category_name = excluded.category_name,
+				category_group_code = excluded.category_group_code,
+				category_group_name = excluded.category_group_name,
+				phone = excluded.phone,
+				address = excluded.address,
+				road_address = excluded.road_address,
+				lat = excluded.lat,
+				lng = excluded.lng,
+				place_url = excluded.place_url,
+				source = excluded.source,
+				updated_at = CURRENT_TIMESTAMP,
+				last_seen = CURRENT_TIMESTAMP
+			RETURNING id
+		`, p.ID, p.PlaceName, p.CategoryName, p.CategoryGroupCode, p.CategoryGroupName,
+			p.Phone, p.AddressName, p.RoadAddressName, lat, lng, p.PlaceURL, models.PlaceSourceSearch).Scan(&id)
+		if err != nil {
+			return err
+		}
+		if err := indexPlace(q, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upsertFavoritePlace adds a favorited place to the catalog. Places
+// already known from search keep their data; only empty fields are filled.
+func upsertFavoritePlace(q querier, f *models.Favorite) error {
+	var id int64
+	err := q.QueryRow(`
+		INSERT INTO places (place_id, place_name, category_name, phone, address, road_address,
+			lat, lng, source, first_seen, last_seen)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+		ON CONFLICT(place_id) DO UPDATE SET
+			category_name = COALESCE(NULLIF(places.category_name, ''), excluded.category_name),
+			phone = COALESCE(NULLIF(places.phone, ''), excluded.phone),
+			address = COALESCE(NULLIF(places.address, ''), excluded.address),
+			road_address = COALESCE(NULLIF(places.road_address, ''), excluded.road_address),
+			last_seen = CURRENT_TIMESTAMP
+		RETURNING id
+	`, f.PlaceID, f.PlaceName, f.Category, f.Phone, f.Address, f.RoadAddress,
+		f.Lat, f.Lng, models.PlaceSourceFavorite).Scan(&id)
+	if err != nil {
+		return err
+	}
+	return indexPlace(q, id)
+}
+
+// catalogFavorites adds the places of a user's live favorites to the
+// catalog, for favorites written without going through Add
+func catalogFavorites(q querier, userID string, placeIDs []string) error {
+	if len(placeIDs) == 0 {
+		return nil
+	}
+	args := []interface{}{userID}
+	for _, id := range placeIDs {
+		args = append(args, id)
+	}
+	rows, err := q.Query(`
+		SELECT `+favoriteColumns+` FROM favorites
+		WHERE user_id = ? AND deleted_at IS NULL
+			AND place_id IN (?`+strings.Repeat(", ?", len(placeIDs)-1)+`)
+	`, args...)
+	if err != nil {
+		return err
+	}
+	var favorites []models.Favorite
+	for rows.Next() {
+		f, err := scanFavorite(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		favorites = append(favorites, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range favorites {
+		if err := upsertFavoritePlace(q, &favorites[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexPlace rewrites the full-text index entry of a place
+func indexPlace(q querier, id int64) error {
+	var name, address, roadAddress, category sql.NullString
+	err := q.QueryRow(`
+		SELECT place_name, address, road_address, category_name FROM places WHERE id = ?
+	`, id).
+		Scan(&name, &address, &roadAddress, &category)
+	if err != nil {
+		return err
+	}
+	if _, err := q.Exec("DELETE FROM places_fts WHERE rowid = ?", id); err != nil {
+		return err
+	}
+	_, err = q.Exec(`INSERT INTO places_fts (rowid, name, address, category) VALUES (?, ?, ?, ?)`,
+		id, textnorm.Bigrams(name.String),
+		textnorm.Bigrams(address.String)+" "+textnorm.Bigrams(roadAddress.String),
+		textnorm.Bigrams(category.String))
+	return err
+}
+
+// recordAppearances notes that places were returned for keyword
+func recordAppearances(q querier, keyword string, places []models.Place) error {
+	key := textnorm.Key(keyword)
+	if key == "" {
+		return nil
+	}
+	for _, p := range places {
+		if p.ID == "" {
+			continue
+		}
+		_, err := q.Exec(`
+			INSERT INTO place_appearances (place_id, normalized_keyword, keyword, first_seen, last_seen)
+			VALUES (?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			ON CONFLICT(place_id, normalized_keyword) DO UPDATE SET
+				keyword = excluded.keyword,
+				count = count + 1,
+				last_seen = CURRENT_TIMESTAMP
+		`, p.ID, key, keyword)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// placeColumns is the column list read by scanPlace
+const placeColumns = `p.place_id, p.place_name, p.category_name, p.category_group_code, p.category_group_name,
+	p.phone, p.address, p.road_address, p.lat, p.lng, p.place_url`
+
+// scanPlace reads a place selected with placeColumns, plus any extra
+// destinations that follow them
+func scanPlace(s rowScanner, extra ...interface{}) (models.Place, error) {
+	var p models.Place
+	var category, groupCode, groupName, phone, address, roadAddress, placeURL sql.NullString
+	var lat, lng sql.NullFloat64
+	dest := append([]interface{}{&p.ID, &p.PlaceName, &category, &groupCode, &groupName,
+		&phone, &address, &roadAddress, &lat, &lng, &placeURL}, extra...)
+	if err := s.Scan(dest...); err != nil {
+		return p, err
+	}
+	p.CategoryName = category.String
+	p.CategoryGroupCode = groupCode.String
+	p.CategoryGroupName = groupName.String
+	p.Phone = phone.String
+	p.AddressName = address.String
+	p.RoadAddressName = roadAddress.String
+	p.PlaceURL = placeURL.String
+	if lat.Valid && lng.Valid {
+		p.Y = strconv.FormatFloat(lat.Float64, 'f', -1, 64)
+		p.X = strconv.FormatFloat(lng.Float64, 'f', -1, 64)
+	}
+	return p, nil
+}
+
+// Get returns the catalog entry for a place with its favorite count and
+// the keywords it recently appeared for, or nil if it is unknown.
+// Keywords are public here, so hidden ones and those exclude reports are
+// left out. IsFavorite is set when userID has the place in their
+// favorites.
+func (r *PlaceRepository) Get(placeID, userID string, appearanceLimit int, exclude func(keyword string) bool) (*models.PlaceDetail, error) {
+	var d models.PlaceDetail
+	var source sql.NullString
+	var firstSeen, lastSeen sql.NullTime
+	place, err := scanPlace(r.db.QueryRow(`
+		SELECT `+placeColumns+`, p.source, p.first_seen, p.last_seen
+		FROM places p
+		WHERE p.place_id = ?
+	`, placeID), &source, &firstSeen, &lastSeen)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.Place = place
+	d.Source = source.String
+	d.FirstSeen = firstSeen.Time
+	d.LastSeen = lastSeen.Time
+
+	var mine int
+	err = r.db.QueryRow(`
+		SELECT COUNT(*), COALESCE(SUM(user_id = ?), 0)
+		FROM favorites
+		WHERE place_id = ? AND deleted_at IS NULL
+	`, userID, placeID).Scan(&d.FavoriteCount, &mine)
+	if err != nil {
+		return nil, err
+	}
+	d.IsFavorite = mine > 0
+
+	rows, err := r.db.Query(`
+		SELECT a.keyword, a.count, a.first_seen, a.last_seen
+		FROM place_appearances a
+		LEFT JOIN keyword_moderation m ON m.normalized_keyword = a.normalized_keyword
+		WHERE a.place_id = ? AND (m.status IS NULL OR m.status <> 'hidden')
+		ORDER BY a.last_seen DESC
+	`, placeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	d.Appearances = []models.PlaceAppearance{}
+	for rows.Next() && len(d.Appearances) < appearanceLimit {
+		var a models.PlaceAppearance
+		if err := rows.Scan(&a.Keyword, &a.Count, &a.FirstSeen, &a.LastSeen); err != nil {
+			return nil, err
+		}
+		if exclude != nil && exclude(a.Keyword) {
+			continue
+		}
+		d.Appearances = append(d.Appearances, a)
+	}
+	return &d, rows.Err()
+}
+
+// ftsQuery turns user input into an FTS5 query matching all of its
+// bigrams. A single character matches any bigram starting with it.
+func ftsQuery(input string) string {
+	tokens := strings.Fields(textnorm.Bigrams(input))
+	if len(tokens) == 0 {
+		return ""
+	}
+	if len(tokens) == 1 && len([]rune(tokens[0])) == 1 {
+		return `"` + tokens[0] + `"*`
+	}
+	for i, t := range tokens {
+		tokens[i] = `"` + t + `"`
+	}
+	return strings.Join(tokens, " ")
+}
+
+// Search finds places whose name, address or category contain query,
+// ranked by BM25 with name matches weighted highest
+func (r *PlaceRepository) Search(query string, limit int) ([]models.Place, error) {
+	match := ftsQuery(query)
+	if match == "" {
+		return []models.Place{}, nil
+	}
+
+	rows, err := r.db.Query(`
+		SELECT `+placeColumns+`
+		FROM places_fts
+		JOIN places p ON p.id = places_fts.rowid
+		WHERE places_fts MATCH ?
+		ORDER BY bm25(places_fts, 10.0, 3.0, 1.0)
+		LIMIT ?
+	`, match, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	places := []models.Place{}
+	for rows.Next() {
+		p, err := scanPlace(rows)
+		if err != nil {
+			return nil, err
+		}
+		places = append(places, p)
+	}
+	return places, rows.Err()
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jju-compass/jju-compass-map/internal/models"
+)
+
+// ReportRepository runs analytics queries over the search event stream
+type ReportRepository struct {
+	db *sql.DB
+}
+
+// NewReportRepository creates a new report repository
+func NewReportRepository(db *sql.DB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+// LowResultOptions configures a low-result keyword report
+type LowResultOptions struct {
+	From        time.Time // zero = unbounded
+	To          time.Time // zero = unbounded
+	MaxResults  int       // searches returning at most this many results count
+	MinSearches int       // drop keywords searched fewer times than this
+	Samples     int       // sample timestamps per keyword
+	Limit       int
+}
+
+// LowResultKeywords lists keywords whose searches returned at most
+// opts.MaxResults results, grouped by normalized keyword and ordered by
+// how often they were searched
+func (r *ReportRepository) LowResultKeywords(opts LowResultOptions) ([]models.LowResultKeyword, error) {
+	rangeClause, rangeArgs := timeRange("searched_at", opts.From, opts.To)
+	filter := ` WHERE result_count <= ?` + rangeClause
+	filterArgs := append([]interface{}{opts.MaxResults}, rangeArgs...)
+
+	args := append(append([]interface{}{}, filterArgs...), opts.MinSearches, opts.Limit)
+	rows, err := r.db.Query(`
+		SELECT normalized_keyword, COUNT(*), SUM(result_count = 0), COUNT(DISTINCT user_id),
+			MAX(result_count), MIN(searched_at), MAX(searched_at), MAX(id)
+		FROM search_events`+filter+`
+		GROUP BY normalized_keyword
+		HAVING COUNT(*) >= ?
+		ORDER BY COUNT(*) DESC, MAX(searched_at) DESC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var report []models.LowResultKeyword
+	var latestIDs []int64
+	for rows.Next() {
+		var k models.LowResultKeyword
+		var first, last string
+		var latestID int64
+		err := rows.Scan(&k.Normalized, &k.Searches, &k.ZeroResults, &k.Users,
+			&k.MaxResultCount, &first, &last, &latestID)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if k.FirstSearchedAt, err = parseSQLTime(first); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if k.LastSearchedAt, err = parseSQLTime(last); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		report = append(report, k)
+		latestIDs = append(latestIDs, latestID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range report {
+		k := &report[i]
+		if err := r.db.QueryRow("SELECT keyword FROM search_events WHERE id = ?", latestIDs[i]).Scan(&k.Keyword); err != nil {
+			return nil, err
+		}
+		if k.Samples, err = r.samples(k.Normalized, filter, filterArgs, opts.Samples); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// samples returns the most recent matching search times of a keyword
+func (r *ReportRepository) samples(normalized, filter string, filterArgs []interface{}, limit int) ([]time.Time, error) {
+	args := append(append([]interface{}{}, filterArgs...), normalized, limit)
+	rows, err := r.db.Query(`
+		SELECT searched_at FROM search_events`+filter+` AND normalized_keyword = ?
+		ORDER BY searched_at DESC, id DESC
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	samples := []time.Time{}
+	for rows.Next() {
+		var t time.Time
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		samples = append(samples, t)
+	}
+	return samples, rows.Err()
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jju-compass/jju-compass-map/internal/database"
+)
+
+// querier is implemented by *sql.DB and *sql.Tx so that repositories
+// can run the same queries inside or outside a transaction
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// withTx runs fn inside a transaction, committing if it returns nil
+func withTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// sqlTime and parseSQLTime convert times to and from SQLite's
+// CURRENT_TIMESTAMP format, shared with the migrations
+var (
+	sqlTime      = database.FormatTime
+	parseSQLTime = database.ParseTime
+)
+
+// timeRange builds a WHERE fragment restricting column to [from, to).
+// Zero times leave that side unbounded.
+func timeRange(column string, from, to time.Time) (string, []interface{}) {
+	var clause string
+	var args []interface{}
+	if !from.IsZero() {
+		clause += " AND " + column + " >= ?"
+		args = append(args, sqlTime(from))
+	}
+	if !to.IsZero() {
+		clause += " AND " + column + " < ?"
+		args = append(args, sqlTime(to))
+	}
+	return clause, args
+}
@@ -0,0 +1,337 @@
+package repository
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"strings"
+
+	"github.com/jju-compass/jju-compass-map/internal/models"
+)
+
+// SharedListRepository handles published favorite lists.
+// Public lookups only ever see lists that have not been revoked, and
+// owner operations are scoped to the owner's own lists.
+type SharedListRepository struct {
+	db *sql.DB
+}
+
+// NewSharedListRepository creates a new shared list repository
+func NewSharedListRepository(db *sql.DB) *SharedListRepository {
+	return &SharedListRepository{db: db}
+}
+
+// newSlug generates an unguessable URL-safe slug
+func newSlug() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// sharedPlaceQuery selects the live favorites visible through a list.
+// Favorites deleted after publishing drop out of the list.
+const sharedPlaceQuery = `
+	SELECT f.place_id, f.place_name, f.address, f.road_address, f.lat, f.lng, f.phone, f.category
+	FROM shared_lists l
+	JOIN favorites f ON f.user_id = l.owner_id AND f.deleted_at IS NULL
+	WHERE l.id = ? AND (
+		l.all_favorites = 1 OR
+		f.place_id IN (SELECT place_id FROM shared_list_items WHERE list_id = l.id)
+	)
+	ORDER BY f.created_at DESC`
+
+// Create publishes a list of the owner's favorites.
+// With no place IDs the list follows all of the owner's favorites.
+// ErrNotFound is returned if none of the place IDs is a favorite of the owner.
+func (r *SharedListRepository) Create(ownerID, title, description string, placeIDs []string) (*models.SharedList, error) {
+	slug, err := newSlug()
+	if err != nil {
+		return nil, err
+	}
+
+	var list *models.SharedList
+	err = withTx(r.db, func(tx *sql.Tx) error {
+		result, err := tx.Exec(`
+			INSERT INTO shared_lists (owner_id, slug, title, description, all_favorites)
+			VALUES (?, ?, ?, ?, ?)
+		`, ownerID, slug, title, description, len(placeIDs) == 0)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		if len(placeIDs) > 0 {
+			args := []interface{}{id, ownerID}
+			for _, placeID := range placeIDs {
+				args = append(args, placeID)
+			}
+			result, err := tx.Exec(`
+				INSERT OR IGNORE INTO shared_list_items (list_id, place_id)
+				SELECT ?, place_id FROM favorites
+				WHERE user_id = ? AND deleted_at IS NULL
+				  AND place_id IN (?`+strings.Repeat(", ?", len(placeIDs)-1)+`)
+			`, args...)
+			if err != nil {
+				return err
+			}
+			if added, err := result.RowsAffected(); err != nil {
+				return err
+			} else if added == 0 {
+				return ErrNotFound
+			}
+		}
+
+		list, err = getOwned(tx, ownerID, slug)
+		return err
+	})
+	return list, err
+}
+
+// GetOwned retrieves all of the owner's lists, newest first
+func (r *SharedListRepository) GetOwned(ownerID string) ([]models.SharedList, error) {
+	rows, err := r.db.Query(`
+		SELECT id, owner_id, slug, title, description, all_favorites, created_at, updated_at, revoked_at
+		FROM shared_lists
+		WHERE owner_id = ?
+		ORDER BY created_at DESC, id DESC
+	`, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lists []models.SharedList
+	for rows.Next() {
+		l, err := scanSharedList(rows)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range lists {
+		if lists[i].PlaceIDs, err = listPlaceIDs(r.db, lists[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return lists, nil
+}
+
+// GetPublic retrieves the public view of a list by slug,
+// or nil if no active list has that slug
+func (r *SharedListRepository) GetPublic(slug string) (*models.PublicList, error) {
+	var id int64
+	var description sql.NullString
+	list := &models.PublicList{Slug: slug, Places: []models.SharedPlace{}}
+	err := r.db.QueryRow(`
+		SELECT id, title, description, updated_at
+		FROM shared_lists
+		WHERE slug = ? AND revoked_at IS NULL
+	`, slug).Scan(&id, &list.Title, &description, &list.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	list.Description = description.String
+
+	rows, err := r.db.Query(sharedPlaceQuery, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.SharedPlace
+		var address, roadAddress, phone, category sql.NullString
+		err := rows.Scan(&p.PlaceID, &p.PlaceName, &address, &roadAddress, &p.Lat, &p.Lng, &phone, &category)
+		if err != nil {
+			return nil, err
+		}
+		p.Address = address.String
+		p.RoadAddress = roadAddress.String
+		p.Phone = phone.String
+		p.Category = category.String
+		list.Places = append(list.Places, p)
+	}
+	return list, rows.Err()
+}
+
+// CopyToFavorites adds every place of an active list to the user's favorites.
+// Places the user already has are skipped. ErrNotFound is returned if no
+// active list has that slug.
+func (r *SharedListRepository) CopyToFavorites(slug, userID string) (added, skipped int64, err error) {
+	err = withTx(r.db, func(tx *sql.Tx) error {
+		var id int64
+		err := tx.QueryRow(`
+			SELECT id FROM shared_lists WHERE slug = ? AND revoked_at IS NULL
+		`, slug).Scan(&id)
+		if err == sql.ErrNoRows {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		placeIDs, err := sharedPlaceIDs(tx, id)
+		if err != nil {
+			return err
+		}
+		total := int64(len(placeIDs))
+
+		// Same upsert as FavoriteRepository.Add: revive tombstones, keep live rows
+		result, err := tx.Exec(`
+			INSERT INTO favorites (user_id, place_id, place_name, address, road_address, lat, lng, phone, category, updated_at)
+			SELECT ?, place_id, place_name, address, road_address, lat, lng, phone, category, CURRENT_TIMESTAMP
+			FROM (`+sharedPlaceQuery+`) WHERE true
+			ON CONFLICT(user_id, place_id) DO UPDATE SET
+				place_name = excluded.place_name,
+				address = excluded.address,
+				road_address = excluded.road_address,
+				lat = excluded.lat,
+				lng = excluded.lng,
+				phone = excluded.phone,
+				category = excluded.category,
+				created_at = CURRENT_TIMESTAMP,
+				updated_at = CURRENT_TIMESTAMP,
+				version = favorites.version + 1,
+				deleted_at = NULL
+			WHERE favorites.deleted_at IS NOT NULL
+		`, userID, id)
+		if err != nil {
+			return err
+		}
+		if added, err = result.RowsAffected(); err != nil {
+			return err
+		}
+		skipped = total - added
+		return catalogFavorites(tx, userID, placeIDs)
+	})
+	return added, skipped, err
+}
+
+// sharedPlaceIDs returns the place IDs a list currently shares
+func sharedPlaceIDs(q querier, listID int64) ([]string, error) {
+	rows, err := q.Query(`SELECT place_id FROM (`+sharedPlaceQuery+`)`, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Rotate replaces the slug of one of the owner's active lists, so the old
+// link stops working. ErrNotFound is returned if the owner has no such list.
+func (r *SharedListRepository) Rotate(ownerID, slug string) (*models.SharedList, error) {
+	newSlugValue, err := newSlug()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.db.Exec(`
+		UPDATE shared_lists SET slug = ?, updated_at = CURRENT_TIMESTAMP
+		WHERE slug = ? AND owner_id = ? AND revoked_at IS NULL
+	`, newSlugValue, slug, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if affected == 0 {
+		return nil, ErrNotFound
+	}
+	return getOwned(r.db, ownerID, newSlugValue)
+}
+
+// Revoke unpublishes one of the owner's lists.
+// ErrNotFound is returned if the owner has no such active list.
+func (r *SharedListRepository) Revoke(ownerID, slug string) error {
+	result, err := r.db.Exec(`
+		UPDATE shared_lists SET revoked_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE slug = ? AND owner_id = ? AND revoked_at IS NULL
+	`, slug, ownerID)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// getOwned retrieves one of the owner's lists by slug
+func getOwned(q querier, ownerID, slug string) (*models.SharedList, error) {
+	l, err := scanSharedList(q.QueryRow(`
+		SELECT id, owner_id, slug, title, description, all_favorites, created_at, updated_at, revoked_at
+		FROM shared_lists
+		WHERE slug = ? AND owner_id = ?
+	`, slug, ownerID))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if l.PlaceIDs, err = listPlaceIDs(q, l.ID); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// scanSharedList reads a shared list row
+func scanSharedList(s rowScanner) (models.SharedList, error) {
+	var l models.SharedList
+	var description sql.NullString
+	var revokedAt sql.NullTime
+	err := s.Scan(&l.ID, &l.OwnerID, &l.Slug, &l.Title, &description, &l.AllFavorites,
+		&l.CreatedAt, &l.UpdatedAt, &revokedAt)
+	if err != nil {
+		return l, err
+	}
+	l.Description = description.String
+	if revokedAt.Valid {
+		l.RevokedAt = &revokedAt.Time
+	}
+	return l, nil
+}
+
+// listPlaceIDs returns the explicitly selected places of a list
+func listPlaceIDs(q querier, listID int64) ([]string, error) {
+	rows, err := q.Query("SELECT place_id FROM shared_list_items WHERE list_id = ? ORDER BY place_id", listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var placeIDs []string
+	for rows.Next() {
+		var placeID string
+		if err := rows.Scan(&placeID); err != nil {
+			return nil, err
+		}
+		placeIDs = append(placeIDs, placeID)
+	}
+	return placeIDs, rows.Err()
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jju-compass/jju-compass-map/internal/models"
+)
+
+// trendBucketSize is the granularity of keyword_trend_buckets.
+// Buckets are maintained on every search, so trending queries only
+// read a bounded number of small rows regardless of history size.
+const trendBucketSize = 10 * time.Minute
+
+// TrendRetention is how long trend buckets are kept: two of the longest windows
+const TrendRetention = 14 * 24 * time.Hour
+
+// trendBucket returns the start of the bucket containing t, in Unix seconds
+func trendBucket(t time.Time) int64 {
+	size := int64(trendBucketSize / time.Second)
+	return t.Unix() / size * size
+}
+
+// GetTrending ranks keywords by exponentially decayed search counts over
+// the window ending at now. The half-life is a quarter of the window, so
+// a search counts half as much after a quarter window has passed. Each
+// keyword's rank is compared with its rank over the previous window.
+// Hidden keywords and those matching exclude are left out.
+func (r *HistoryRepository) GetTrending(window time.Duration, limit int, now time.Time, exclude func(keyword string) bool) ([]models.TrendingKeyword, error) {
+	prevStart := now.Add(-2 * window)
+	rows, err := r.db.Query(`
+		SELECT normalized_keyword, bucket_start, keyword, count
+		FROM keyword_trend_buckets
+		WHERE bucket_start >= ? AND normalized_keyword NOT IN (
+			SELECT normalized_keyword FROM keyword_moderation WHERE status = 'hidden'
+		)
+		ORDER BY bucket_start
+	`, trendBucket(prevStart))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type trend struct {
+		keyword     string
+		score, prev float64
+		count       int
+	}
+	halfLife := window.Seconds() / 4
+	boundary := now.Add(-window).Unix()
+	trends := make(map[string]*trend)
+
+	for rows.Next() {
+		var normalized, keyword string
+		var bucketStart int64
+		var count int
+		if err := rows.Scan(&normalized, &bucketStart, &keyword, &count); err != nil {
+			return nil, err
+		}
+
+		t, ok := trends[normalized]
+		if !ok {
+			t = &trend{}
+			trends[normalized] = t
+		}
+		// Rows are ordered by time, so the last one has the latest spelling
+		t.keyword = keyword
+
+		// Score each bucket at its midpoint, against the end of its window
+		mid := float64(bucketStart) + trendBucketSize.Seconds()/2
+		if bucketStart >= boundary {
+			t.score += float64(count) * decay(float64(now.Unix())-mid, halfLife)
+			t.count += count
+		} else {
+			t.prev += float64(count) * decay(float64(boundary)-mid, halfLife)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(trends))
+	for k, t := range trends {
+		if exclude != nil && exclude(t.keyword) {
+			continue
+		}
+		keys = append(keys, k)
+	}
+
+	// Previous ranks over every keyword seen in the previous window
+	sort.Slice(keys, func(i, j int) bool {
+		return trends[keys[i]].prev > trends[keys[j]].prev ||
+			(trends[keys[i]].prev == trends[keys[j]].prev && keys[i] < keys[j])
+	})
+	prevRanks := make(map[string]int)
+	for i, k := range keys {
+		if trends[k].prev > 0 {
+			prevRanks[k] = i + 1
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return trends[keys[i]].score > trends[keys[j]].score ||
+			(trends[keys[i]].score == trends[keys[j]].score && keys[i] < keys[j])
+	})
+
+	var result []models.TrendingKeyword
+	for _, k := range keys {
+		t := trends[k]
+		if t.count == 0 || len(result) >= limit {
+			break
+		}
+		item := models.TrendingKeyword{
+			Keyword: t.keyword,
+			Rank:    len(result) + 1,
+			Score:   math.Round(t.score*100) / 100,
+			Count:   t.count,
+		}
+		if prev, ok := prevRanks[k]; ok {
+			change := prev - item.Rank
+			item.PreviousRank = &prev
+			item.RankChange = &change
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// PruneTrends removes trend buckets older than before
+func (r *HistoryRepository) PruneTrends(before time.Time) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM keyword_trend_buckets WHERE bucket_start < ?", trendBucket(before))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// decay returns the weight of an event age seconds old
+func decay(age, halfLife float64) float64 {
+	if age < 0 {
+		age = 0
+	}
+	return math.Exp(-math.Ln2 * age / halfLife)
+}
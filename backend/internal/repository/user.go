@@ -0,0 +1,282 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jju-compass/jju-compass-map/internal/models"
+	"github.com/jju-compass/jju-compass-map/internal/textnorm"
+)
+
+// sessionTouchInterval limits how often a session's last_seen_at is written
+const sessionTouchInterval = 5 * time.Minute
+
+// UserRepository handles accounts and their login sessions
+type UserRepository struct {
+	db *sql.DB
+}
+
+// NewUserRepository creates a new user repository
+func NewUserRepository(db *sql.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// userColumns is the column list read by scanUser
+const userColumns = `u.id, u.provider, u.nickname, u.profile_image_url, u.is_admin, u.created_at, u.last_login_at`
+
+// scanUser reads a user selected with userColumns, plus any extra destinations
+func scanUser(s rowScanner, extra ...interface{}) (models.User, error) {
+	var u models.User
+	var nickname, image sql.NullString
+	dest := append([]interface{}{&u.ID, &u.Provider, &nickname, &image, &u.IsAdmin, &u.CreatedAt, &u.LastLoginAt}, extra...)
+	if err := s.Scan(dest...); err != nil {
+		return u, err
+	}
+	u.Nickname = nickname.String
+	u.ProfileImageURL = image.String
+	return u, nil
+}
+
+// Login creates the account for a provider identity on first login, or
+// refreshes its profile, and returns it
+func (r *UserRepository) Login(provider, providerUserID, nickname, profileImageURL string) (*models.User, error) {
+	var id int64
+	err := r.db.QueryRow(`
+		INSERT INTO users (provider, provider_user_id, nickname, profile_image_url)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(provider, provider_user_id) DO UPDATE SET
+			nickname = excluded.nickname,
+			profile_image_url = excluded.profile_image_url,
+			last_login_at = CURRENT_TIMESTAMP
+		RETURNING id
+	`, provider, providerUserID, nickname, profileImageURL).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	return r.Get(id)
+}
+
+// Get retrieves an account, or nil if it does not exist
+func (r *UserRepository) Get(id int64) (*models.User, error) {
+	u, err := scanUser(r.db.QueryRow(`SELECT `+userColumns+` FROM users u WHERE u.id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// hashToken returns the stored form of a session token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateSession starts a session for a user and returns its token.
+// Only a hash of the token is stored.
+func (r *UserRepository) CreateSession(userID int64, userAgent string, ttl time.Duration) (string, error) {
+	token, err := newSlug()
+	if err != nil {
+		return "", err
+	}
+	_, err = r.db.Exec(`
+		INSERT INTO sessions (token_hash, user_id, user_agent, expires_at)
+		VALUES (?, ?, ?, ?)
+	`, hashToken(token), userID, userAgent, sqlTime(time.Now().Add(ttl)))
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Authenticate resolves a session token to its session and user, or nil
+// if the session is unknown, expired or revoked
+func (r *UserRepository) Authenticate(token string) (*models.Session, *models.User, error) {
+	var s models.Session
+	var userAgent sql.NullString
+	u, err := scanUser(r.db.QueryRow(`
+		SELECT `+userColumns+`, s.id, s.user_agent, s.created_at, s.last_seen_at, s.expires_at
+		FROM sessions s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.token_hash = ? AND s.revoked_at IS NULL AND s.expires_at > ?
+	`, hashToken(token), sqlTime(time.Now())), &s.ID, &userAgent, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	s.UserID = u.ID
+	s.UserAgent = userAgent.String
+	s.Current = true
+
+	if time.Since(s.LastSeenAt) > sessionTouchInterval {
+		_, err := r.db.Exec("UPDATE sessions SET last_seen_at = CURRENT_TIMESTAMP WHERE id = ?", s.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return &s, &u, nil
+}
+
+// Sessions lists a user's active sessions, most recently used first
+func (r *UserRepository) Sessions(userID int64) ([]models.Session, error) {
+	rows, err := r.db.Query(`
+		SELECT id, user_agent, created_at, last_seen_at, expires_at
+		FROM sessions
+		WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+		ORDER BY last_seen_at DESC
+	`, userID, sqlTime(time.Now()))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+	for rows.Next() {
+		s := models.Session{UserID: userID}
+		var userAgent sql.NullString
+		if err := rows.Scan(&s.ID, &userAgent, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt); err != nil {
+			return nil, err
+		}
+		s.UserAgent = userAgent.String
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// ClaimLegacyID records the adoption of a user ID from the unsigned
+// identity cookie. It reports false if the ID was adopted before or owns
+// no favorites or search history, so made-up IDs are never recorded.
+func (r *UserRepository) ClaimLegacyID(userID string) (bool, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO legacy_identity_claims (id_hash)
+		SELECT ? WHERE EXISTS (SELECT 1 FROM favorites WHERE user_id = ?)
+			OR EXISTS (SELECT 1 FROM search_history WHERE user_id = ?)
+		ON CONFLICT(id_hash) DO NOTHING
+	`, hashToken(userID), userID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+// RevokeSession ends a single session
+func (r *UserRepository) RevokeSession(sessionID int64) error {
+	_, err := r.db.Exec(`
+		UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP WHERE id = ? AND revoked_at IS NULL
+	`, sessionID)
+	return err
+}
+
+// RevokeAllSessions ends every session of a user and returns how many
+// were active
+func (r *UserRepository) RevokeAllSessions(userID int64) (int64, error) {
+	result, err := r.db.Exec(`
+		UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP
+		WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?
+	`, userID, sqlTime(time.Now()))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// PurgeSessions removes sessions that expired or were revoked before the given time
+func (r *UserRepository) PurgeSessions(before time.Time) (int64, error) {
+	result, err := r.db.Exec(`
+		DELETE FROM sessions WHERE expires_at < ? OR revoked_at < ?
+	`, sqlTime(before), sqlTime(before))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// userScanLimit caps how many accounts Find reads when matching nicknames
+const userScanLimit = 1000
+
+// Find looks accounts up for operators. A numeric query or a "user_<id>"
+// subject matches that account; anything else matches nicknames,
+// most recent logins first.
+func (r *UserRepository) Find(query string, limit int) ([]models.User, error) {
+	query = strings.TrimSpace(query)
+	if id, err := strconv.ParseInt(strings.TrimPrefix(query, "user_"), 10, 64); err == nil {
+		u, err := r.Get(id)
+		if err != nil || u == nil {
+			return nil, err
+		}
+		return []models.User{*u}, nil
+	}
+
+	rows, err := r.db.Query(`
+		SELECT `+userColumns+` FROM users u ORDER BY u.last_login_at DESC LIMIT ?
+	`, userScanLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() && len(users) < limit {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		if query == "" || textnorm.Contains(u.Nickname, query) {
+			users = append(users, u)
+		}
+	}
+	return users, rows.Err()
+}
+
+// Lookup returns an account with the amount of data stored under it,
+// or nil if it does not exist
+func (r *UserRepository) Lookup(id int64) (*models.UserLookup, error) {
+	var l models.UserLookup
+	u, err := scanUser(r.db.QueryRow(`
+		SELECT `+userColumns+`, u.provider_user_id FROM users u WHERE u.id = ?
+	`, id), &l.ProviderUserID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	l.User = u
+	l.Subject = u.Subject()
+
+	err = r.db.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM favorites WHERE user_id = ? AND deleted_at IS NULL),
+			(SELECT COUNT(*) FROM search_history WHERE user_id = ? AND deleted_at IS NULL),
+			(SELECT COUNT(*) FROM sessions WHERE user_id = ? AND revoked_at IS NULL AND expires_at > ?)
+	`, l.Subject, l.Subject, id, sqlTime(time.Now())).Scan(&l.Favorites, &l.History, &l.ActiveSessions)
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// SetAdmin grants or revokes operator access for an account
+func (r *UserRepository) SetAdmin(id int64, isAdmin bool) error {
+	result, err := r.db.Exec("UPDATE users SET is_admin = ? WHERE id = ?", isAdmin, id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
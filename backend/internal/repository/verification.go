@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/jju-compass/jju-compass-map/internal/models"
+)
+
+// VerificationRepository handles place re-verification results
+type VerificationRepository struct {
+	db *sql.DB
+}
+
+// NewVerificationRepository creates a new verification repository
+func NewVerificationRepository(db *sql.DB) *VerificationRepository {
+	return &VerificationRepository{db: db}
+}
+
+// Due returns one representative favorite for each favorited place that
+// has never been verified or was last verified before olderThan,
+// least recently checked first
+func (r *VerificationRepository) Due(olderThan time.Time, limit int) ([]models.Favorite, error) {
+	rows, err := r.db.Query(`
+		SELECT `+favoriteColumns+`
+		FROM favorites
+		WHERE id IN (
+			SELECT MIN(f.id)
+			FROM favorites f
+			LEFT JOIN place_verifications v ON v.place_id = f.place_id
+			WHERE f.deleted_at IS NULL AND (v.checked_at IS NULL OR v.checked_at < ?)
+			GROUP BY f.place_id
+		)
+		ORDER BY (
+			SELECT checked_at FROM place_verifications v WHERE v.place_id = favorites.place_id
+		) ASC
+		LIMIT ?
+	`, sqlTime(olderThan), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var favorites []models.Favorite
+	for rows.Next() {
+		f, err := scanFavorite(rows)
+		if err != nil {
+			return nil, err
+		}
+		favorites = append(favorites, f)
+	}
+	return favorites, rows.Err()
+}
+
+// Save stores the latest verification of a place
+func (r *VerificationRepository) Save(v *models.PlaceVerification) error {
+	_, err := r.db.Exec(`
+		INSERT INTO place_verifications (place_id, status, place_name, address, road_address, phone, category, lat, lng, checked_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(place_id) DO UPDATE SET
+			status = excluded.status,
+			place_name = excluded.place_name,
+			address = excluded.address,
+			road_address = excluded.road_address,
+			phone = excluded.phone,
+			category = excluded.category,
+			lat = excluded.lat,
+			lng = excluded.lng,
+			checked_at = CURRENT_TIMESTAMP
+	`, v.PlaceID, v.Status, v.PlaceName, v.Address, v.RoadAddress, v.Phone, v.Category, v.Lat, v.Lng)
+	return err
+}
+
+// GetMany returns the verifications for the given places, keyed by place ID
+func (r *VerificationRepository) GetMany(placeIDs []string) (map[string]*models.PlaceVerification, error) {
+	result := make(map[string]*models.PlaceVerification)
+	if len(placeIDs) == 0 {
+		return result, nil
+	}
+
+	args := make([]interface{}, len(placeIDs))
+	for i, id := range placeIDs {
+		args[i] = id
+	}
+	rows, err := r.db.Query(`
+		SELECT place_id, status, place_name, address, road_address, phone, category, lat, lng, checked_at
+		FROM place_verifications
+		WHERE place_id IN (?`+strings.Repeat(", ?", len(placeIDs)-1)+`)
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v models.PlaceVerification
+		var name, address, roadAddress, phone, category sql.NullString
+		var lat, lng sql.NullFloat64
+		err := rows.Scan(&v.PlaceID, &v.Status, &name, &address, &roadAddress,
+			&phone, &category, &lat, &lng, &v.CheckedAt)
+		if err != nil {
+			return nil, err
+		}
+		v.PlaceName = name.String
+		v.Address = address.String
+		v.RoadAddress = roadAddress.String
+		v.Phone = phone.String
+		v.Category = category.String
+		v.Lat = lat.Float64
+		v.Lng = lng.Float64
+		result[v.PlaceID] = &v
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jju-compass/jju-compass-map/internal/models"
+)
+
+// monthOffset shifts UTC timestamps so that months are counted in KST
+const monthOffset = "+9 hours"
+
+// uncategorized is the category used for visits without one
+const uncategorized = "기타"
+
+// VisitRepository handles visit log operations
+type VisitRepository struct {
+	db *sql.DB
+}
+
+// NewVisitRepository creates a new visit repository
+func NewVisitRepository(db *sql.DB) *VisitRepository {
+	return &VisitRepository{db: db}
+}
+
+// Add logs a new visit
+func (r *VisitRepository) Add(v *models.Visit) error {
+	result, err := r.db.Exec(`
+		INSERT INTO visits (user_id, place_id, place_name, category, lat, lng, visited_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, v.UserID, v.PlaceID, v.PlaceName, v.Category, v.Lat, v.Lng, sqlTime(v.VisitedAt))
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	v.ID = id
+	return nil
+}
+
+// List retrieves a user's visits in [from, to), newest first
+func (r *VisitRepository) List(userID string, from, to time.Time, limit int) ([]models.Visit, error) {
+	clause, args := timeRange("visited_at", from, to)
+	rows, err := r.db.Query(`
+		SELECT id, user_id, place_id, place_name, category, lat, lng, visited_at, created_at
+		FROM visits
+		WHERE user_id = ?`+clause+`
+		ORDER BY visited_at DESC
+		LIMIT ?
+	`, append(append([]interface{}{userID}, args...), limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var visits []models.Visit
+	for rows.Next() {
+		var v models.Visit
+		var category sql.NullString
+		var lat, lng sql.NullFloat64
+		err := rows.Scan(&v.ID, &v.UserID, &v.PlaceID, &v.PlaceName, &category,
+			&lat, &lng, &v.VisitedAt, &v.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		v.Category = category.String
+		v.Lat = lat.Float64
+		v.Lng = lng.Float64
+		visits = append(visits, v)
+	}
+	return visits, rows.Err()
+}
+
+// Summaries returns visit counts and last visit times for the given places, keyed by place ID
+func (r *VisitRepository) Summaries(userID string, placeIDs []string) (map[string]*models.PlaceVisitSummary, error) {
+	result := make(map[string]*models.PlaceVisitSummary)
+	if len(placeIDs) == 0 {
+		return result, nil
+	}
+
+	args := []interface{}{userID}
+	for _, id := range placeIDs {
+		args = append(args, id)
+	}
+	rows, err := r.db.Query(`
+		SELECT place_id, COUNT(*), MAX(visited_at)
+		FROM visits
+		WHERE user_id = ? AND place_id IN (?`+strings.Repeat(", ?", len(placeIDs)-1)+`)
+		GROUP BY place_id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.PlaceVisitSummary
+		var last string
+		if err := rows.Scan(&s.PlaceID, &s.Count, &last); err != nil {
+			return nil, err
+		}
+		if s.LastVisitedAt, err = parseSQLTime(last); err != nil {
+			return nil, err
+		}
+		result[s.PlaceID] = &s
+	}
+	return result, rows.Err()
+}
+
+// Stats summarizes a user's visits in [from, to): monthly totals per
+// top-level category and the topN most visited places
+func (r *VisitRepository) Stats(userID string, from, to time.Time, topN int) (*models.VisitStats, error) {
+	clause, rangeArgs := timeRange("visited_at", from, to)
+	args := append([]interface{}{userID}, rangeArgs...)
+
+	stats := &models.VisitStats{
+		Monthly:   []models.MonthlyCategoryVisits{},
+		TopPlaces: []models.PlaceVisitSummary{},
+	}
+
+	rows, err := r.db.Query(`
+		SELECT strftime('%Y-%m', visited_at, '`+monthOffset+`') AS month, COALESCE(category, ''), COUNT(*)
+		FROM visits
+		WHERE user_id = ?`+clause+`
+		GROUP BY month, category
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Kakao categories look like "음식점 > 카페 > 커피전문점";
+	// totals are grouped by the first segment.
+	type key struct{ month, category string }
+	counts := make(map[key]int)
+	for rows.Next() {
+		var month, category string
+		var count int
+		if err := rows.Scan(&month, &category, &count); err != nil {
+			return nil, err
+		}
+		counts[key{month, topCategory(category)}] += count
+		stats.Total += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for k, count := range counts {
+		stats.Monthly = append(stats.Monthly, models.MonthlyCategoryVisits{Month: k.month, Category: k.category, Count: count})
+	}
+	sort.Slice(stats.Monthly, func(i, j int) bool {
+		if stats.Monthly[i].Month != stats.Monthly[j].Month {
+			return stats.Monthly[i].Month < stats.Monthly[j].Month
+		}
+		return stats.Monthly[i].Count > stats.Monthly[j].Count
+	})
+
+	topRows, err := r.db.Query(`
+		SELECT place_id, MAX(place_name), COALESCE(MAX(category), ''), COUNT(*) AS count, MAX(visited_at) AS last
+		FROM visits
+		WHERE user_id = ?`+clause+`
+		GROUP BY place_id
+		ORDER BY count DESC, last DESC
+		LIMIT ?
+	`, append(args, topN)...)
+	if err != nil {
+		return nil, err
+	}
+	defer topRows.Close()
+
+	for topRows.Next() {
+		var s models.PlaceVisitSummary
+		var last string
+		if err := topRows.Scan(&s.PlaceID, &s.PlaceName, &s.Category, &s.Count, &last); err != nil {
+			return nil, err
+		}
+		if s.LastVisitedAt, err = parseSQLTime(last); err != nil {
+			return nil, err
+		}
+		stats.TopPlaces = append(stats.TopPlaces, s)
+	}
+	return stats, topRows.Err()
+}
+
+// topCategory returns the first segment of a Kakao category name
+func topCategory(category string) string {
+	top := strings.TrimSpace(strings.SplitN(category, ">", 2)[0])
+	if top == "" {
+		return uncategorized
+	}
+	return top
+}
@@ -0,0 +1,188 @@
+package suggest
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/jju-compass/jju-compass-map/internal/textnorm"
+)
+
+// Suggestion kinds
+const (
+	KindHistory  = "history"
+	KindPopular  = "popular"
+	KindPlace    = "place"
+	KindCategory = "category"
+)
+
+// topK is the number of best entries cached at every trie node.
+// Lookups read the cache at the prefix node instead of walking the subtree.
+const topK = 32
+
+// Suggestion is a single completion
+type Suggestion struct {
+	Text  string  `json:"text"`
+	Kind  string  `json:"kind"`
+	Score float64 `json:"score"`
+}
+
+// entry is a term stored in the tries
+type entry struct {
+	key     string // textnorm.Key of the text
+	text    string // display text
+	kind    string
+	weight  float64
+	removed bool
+}
+
+// node is a trie node keyed by rune
+type node struct {
+	children map[rune]*node
+	top      []*entry // best entries in this subtree, by weight
+}
+
+// Index is an in-memory prefix index of global completions: popular
+// keywords, cached place names and category names. Terms are indexed
+// jamo by jamo, so half-typed syllables match, and by initial consonants.
+// It is safe for concurrent use and can be updated incrementally or
+// rebuilt wholesale.
+type Index struct {
+	mu       sync.RWMutex
+	jamo     *node             // keyed by textnorm.Decompose(key)
+	initials *node             // keyed by textnorm.Choseong(key)
+	entries  map[string]*entry // by kind + key
+}
+
+// NewIndex creates an empty index
+func NewIndex() *Index {
+	return &Index{jamo: &node{}, initials: &node{}, entries: make(map[string]*entry)}
+}
+
+// Add inserts a term, or raises its weight if it is already indexed
+func (idx *Index) Add(text, kind string, weight float64) {
+	key := textnorm.Key(text)
+	if key == "" {
+		return
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	e, ok := idx.entries[kind+"\x00"+key]
+	if !ok {
+		e = &entry{key: key, kind: kind}
+		idx.entries[kind+"\x00"+key] = e
+	}
+	e.text = text
+	if weight > e.weight {
+		e.weight = weight
+	}
+
+	idx.jamo.insert(textnorm.Decompose(key), e)
+	idx.initials.insert(textnorm.Choseong(key), e)
+}
+
+// Remove drops a term. Its slots in the trie stay empty until the next
+// rebuild, so a prefix may briefly offer fewer completions.
+func (idx *Index) Remove(text, kind string) {
+	key := textnorm.Key(text)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if e, ok := idx.entries[kind+"\x00"+key]; ok {
+		e.removed = true
+		delete(idx.entries, kind+"\x00"+key)
+	}
+}
+
+// insert offers e to every node along path, creating nodes as needed
+func (n *node) insert(path string, e *entry) {
+	n.offer(e)
+	for _, r := range path {
+		child, ok := n.children[r]
+		if !ok {
+			if n.children == nil {
+				n.children = make(map[rune]*node)
+			}
+			child = &node{}
+			n.children[r] = child
+		}
+		n = child
+		n.offer(e)
+	}
+}
+
+// offer places e in the node's top list if it ranks high enough
+func (n *node) offer(e *entry) {
+	found := false
+	for _, t := range n.top {
+		if t == e {
+			found = true
+			break
+		}
+	}
+	if !found {
+		if len(n.top) >= topK && n.top[len(n.top)-1].weight >= e.weight {
+			return
+		}
+		n.top = append(n.top, e)
+	}
+	sort.SliceStable(n.top, func(i, j int) bool { return n.top[i].weight > n.top[j].weight })
+	if len(n.top) > topK {
+		n.top = n.top[:topK]
+	}
+}
+
+// Search returns up to limit completions for prefix, best first.
+// A prefix of only consonants such as "ㅅㅌ" is matched against initials.
+func (idx *Index) Search(prefix string, limit int) []Suggestion {
+	key := textnorm.Key(prefix)
+	if key == "" {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	n, path := idx.jamo, textnorm.Decompose(key)
+	if textnorm.IsChoseong(key) {
+		n, path = idx.initials, key
+	}
+	for _, r := range path {
+		n = n.children[r]
+		if n == nil {
+			return nil
+		}
+	}
+
+	var result []Suggestion
+	for _, e := range n.top {
+		if len(result) >= limit {
+			break
+		}
+		if e.removed {
+			continue
+		}
+		result = append(result, Suggestion{Text: e.text, Kind: e.kind, Score: e.weight})
+	}
+	return result
+}
+
+// Replace swaps in the contents of another index, used after a full rebuild
+func (idx *Index) Replace(other *Index) {
+	other.mu.RLock()
+	jamo, initials, entries := other.jamo, other.initials, other.entries
+	other.mu.RUnlock()
+
+	idx.mu.Lock()
+	idx.jamo, idx.initials, idx.entries = jamo, initials, entries
+	idx.mu.Unlock()
+}
+
+// Len returns the number of indexed terms
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.entries)
+}
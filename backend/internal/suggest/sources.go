@@ -0,0 +1,50 @@
+package suggest
+
+import (
+	"math"
+	"strings"
+
+	"github.com/jju-compass/jju-compass-map/internal/models"
+)
+
+// Base weights by kind. Popular keywords also grow with their search count.
+const (
+	categoryWeight = 2.0
+	placeWeight    = 1.0
+)
+
+// Categories are the Kakao category group names, always suggested
+var Categories = []string{
+	"대형마트", "편의점", "어린이집", "유치원", "학교", "학원", "주차장", "주유소",
+	"지하철역", "은행", "문화시설", "중개업소", "공공기관", "관광명소", "숙박",
+	"음식점", "카페", "병원", "약국",
+}
+
+// PopularWeight converts a search count into a weight
+func PopularWeight(count int) float64 {
+	return 1 + math.Log1p(float64(count))
+}
+
+// AddPopular indexes popular keywords
+func (idx *Index) AddPopular(keywords []models.PopularKeyword) {
+	for _, k := range keywords {
+		idx.Add(k.Keyword, KindPopular, PopularWeight(k.Count))
+	}
+}
+
+// AddPlaces indexes place names and their category segments
+func (idx *Index) AddPlaces(places []models.Place) {
+	for _, p := range places {
+		idx.Add(p.PlaceName, KindPlace, placeWeight)
+		for _, segment := range strings.Split(p.CategoryName, ">") {
+			idx.Add(strings.TrimSpace(segment), KindCategory, categoryWeight)
+		}
+	}
+}
+
+// AddCategories indexes the fixed category names
+func (idx *Index) AddCategories() {
+	for _, c := range Categories {
+		idx.Add(c, KindCategory, categoryWeight)
+	}
+}
@@ -0,0 +1,166 @@
+package textnorm
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Hangul syllable block layout (Unicode 3.12 "Conjoining Jamo Behavior")
+const (
+	syllableBase  = 0xAC00
+	syllableLast  = 0xD7A3
+	jungseongSize = 21
+	jongseongSize = 28
+)
+
+// Compatibility jamo, which is what keyboards produce for lone jamo
+var (
+	choseong  = []rune("ㄱㄲㄴㄷㄸㄹㅁㅂㅃㅅㅆㅇㅈㅉㅊㅋㅌㅍㅎ")
+	jungseong = []rune("ㅏㅐㅑㅒㅓㅔㅕㅖㅗㅘㅙㅚㅛㅜㅝㅞㅟㅠㅡㅢㅣ")
+	jongseong = []rune("\x00ㄱㄲㄳㄴㄵㄶㄷㄹㄺㄻㄼㄽㄾㄿㅀㅁㅂㅄㅅㅆㅇㅈㅊㅋㅌㅍㅎ")
+)
+
+// compound splits jamo typed as two keystrokes, so that a half-typed
+// syllable such as "달" still matches "닭" (ㄷㅏㄹㄱ)
+var compound = map[rune]string{
+	'ㅘ': "ㅗㅏ", 'ㅙ': "ㅗㅐ", 'ㅚ': "ㅗㅣ", 'ㅝ': "ㅜㅓ", 'ㅞ': "ㅜㅔ", 'ㅟ': "ㅜㅣ", 'ㅢ': "ㅡㅣ",
+	'ㄳ': "ㄱㅅ", 'ㄵ': "ㄴㅈ", 'ㄶ': "ㄴㅎ", 'ㄺ': "ㄹㄱ", 'ㄻ': "ㄹㅁ", 'ㄼ': "ㄹㅂ",
+	'ㄽ': "ㄹㅅ", 'ㄾ': "ㄹㅌ", 'ㄿ': "ㄹㅍ", 'ㅀ': "ㄹㅎ", 'ㅄ': "ㅂㅅ",
+}
+
+// Normalize folds full-width characters to their ASCII forms, lower-cases
+// letters, and trims and collapses whitespace
+func Normalize(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	space := false
+	for _, r := range s {
+		switch {
+		case r >= 0xFF01 && r <= 0xFF5E: // full-width ASCII
+			r -= 0xFEE0
+		case r == 0x3000: // ideographic space
+			r = ' '
+		}
+		if unicode.IsSpace(r) {
+			space = b.Len() > 0
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// Key is the grouping key for a keyword: Normalize with all whitespace
+// removed, so "스타 벅스" and "스타벅스" are the same keyword
+func Key(s string) string {
+	return strings.ReplaceAll(Normalize(s), " ", "")
+}
+
+// Decompose spells Hangul syllables out as compatibility jamo, splitting
+// compound vowels and finals. Other characters are kept as they are.
+func Decompose(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) * 3)
+	for _, r := range s {
+		if r >= syllableBase && r <= syllableLast {
+			index := int(r - syllableBase)
+			writeJamo(&b, choseong[index/(jungseongSize*jongseongSize)])
+			writeJamo(&b, jungseong[index%(jungseongSize*jongseongSize)/jongseongSize])
+			if final := index % jongseongSize; final != 0 {
+				writeJamo(&b, jongseong[final])
+			}
+			continue
+		}
+		writeJamo(&b, r)
+	}
+	return b.String()
+}
+
+// writeJamo writes a jamo, split if it is compound
+func writeJamo(b *strings.Builder, r rune) {
+	if parts, ok := compound[r]; ok {
+		b.WriteString(parts)
+		return
+	}
+	b.WriteRune(r)
+}
+
+// Choseong replaces each Hangul syllable with its initial consonant,
+// so "스타벅스" becomes "ㅅㅌㅂㅅ". Other characters are kept as they are.
+func Choseong(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range s {
+		if r >= syllableBase && r <= syllableLast {
+			r = choseong[int(r-syllableBase)/(jungseongSize*jongseongSize)]
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// IsChoseong reports whether s consists only of consonant jamo and spaces,
+// as when a user types initials such as "ㅅㅌㅂㅅ"
+func IsChoseong(s string) bool {
+	found := false
+	for _, r := range s {
+		if r == ' ' {
+			continue
+		}
+		if r < 'ㄱ' || r > 'ㅎ' {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// MatchPrefix reports whether text starts with what the user typed,
+// comparing keys jamo by jamo, or by initials when query is only initials
+func MatchPrefix(text, query string) bool {
+	t, q := Key(text), Key(query)
+	if IsChoseong(q) {
+		return strings.HasPrefix(Choseong(t), q)
+	}
+	return strings.HasPrefix(Decompose(t), Decompose(q))
+}
+
+// Contains reports whether text contains what the user typed,
+// with the same matching rules as MatchPrefix
+func Contains(text, query string) bool {
+	t, q := Key(text), Key(query)
+	if IsChoseong(q) {
+		return strings.Contains(Choseong(t), q)
+	}
+	return strings.Contains(Decompose(t), Decompose(q))
+}
+
+// Bigrams splits the key of s into overlapping two-character tokens joined
+// by spaces, for full-text indexing of Korean text, which has no reliable
+// word boundaries. Punctuation is dropped; a single character is returned
+// as it is.
+func Bigrams(s string) string {
+	var runes []rune
+	for _, r := range Key(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			runes = append(runes, r)
+		}
+	}
+	if len(runes) < 2 {
+		return string(runes)
+	}
+	var b strings.Builder
+	b.Grow(len(runes) * 7)
+	for i := 0; i+1 < len(runes); i++ {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteRune(runes[i])
+		b.WriteRune(runes[i+1])
+	}
+	return b.String()
+}
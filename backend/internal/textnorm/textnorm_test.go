@@ -0,0 +1,155 @@
+package textnorm
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"카페", "카페"},
+		{"ＣＡＦＥ", "cafe"},
+		{"Ａ１ 편의점", "a1 편의점"},
+		{"  스타　 벅스  ", "스타 벅스"},
+		{"\t카페\n라떼", "카페 라떼"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"스타 벅스", "스타벅스"},
+		{"스타벅스", "스타벅스"},
+		{"ＳＴＡＲ　bucks", "starbucks"},
+		{" 전주  한옥마을 ", "전주한옥마을"},
+	}
+	for _, tt := range tests {
+		if got := Key(tt.in); got != tt.want {
+			t.Errorf("Key(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDecompose(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"한글", "ㅎㅏㄴㄱㅡㄹ"},
+		// Compound finals and vowels are split into their keystrokes
+		{"닭", "ㄷㅏㄹㄱ"},
+		{"과", "ㄱㅗㅏ"},
+		{"쉐", "ㅅㅜㅔ"},
+		{"ㅘ", "ㅗㅏ"},
+		{"cu편의점", "cuㅍㅕㄴㅇㅡㅣㅈㅓㅁ"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Decompose(tt.in); got != tt.want {
+			t.Errorf("Decompose(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestChoseong(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"스타벅스", "ㅅㅌㅂㅅ"},
+		{"CU 편의점", "CU ㅍㅇㅈ"},
+		{"ㄱ", "ㄱ"},
+	}
+	for _, tt := range tests {
+		if got := Choseong(tt.in); got != tt.want {
+			t.Errorf("Choseong(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIsChoseong(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"ㅅㅌㅂㅅ", true},
+		{"ㅅㅌ ㅂㅅ", true},
+		{"ㄲ", true},
+		{"ㅅa", false},
+		{"ㅏ", false},
+		{"스", false},
+		{" ", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsChoseong(tt.in); got != tt.want {
+			t.Errorf("IsChoseong(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMatchPrefix(t *testing.T) {
+	tests := []struct {
+		name        string
+		text, query string
+		want        bool
+	}{
+		{"whole syllables", "스타벅스", "스타", true},
+		{"half-typed final", "닭갈비", "달", true},
+		{"half-typed next syllable", "닭갈비", "닭ㄱ", true},
+		{"final typed before the next vowel", "스타벅스", "스탑", true},
+		{"half-typed compound vowel", "과자", "고", true},
+		{"different final", "카페", "카펙", false},
+		{"not at the start", "스타벅스", "타", false},
+		{"initials", "스타벅스 전주점", "ㅅㅌ", true},
+		{"initials across a space", "스타 벅스", "ㅅㅌㅂ", true},
+		{"initials not at the start", "스타벅스", "ㅂㅅ", false},
+		{"full-width text", "ＣＵ 편의점", "cu", true},
+		{"spaces in the query", "스타벅스", "스타 벅", true},
+	}
+	for _, tt := range tests {
+		if got := MatchPrefix(tt.text, tt.query); got != tt.want {
+			t.Errorf("%s: MatchPrefix(%q, %q) = %v, want %v", tt.name, tt.text, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		text, query string
+		want        bool
+	}{
+		{"스타벅스 전주점", "전주", true},
+		{"스타벅스 전주점", "전ㅈ", true},
+		{"스타벅스", "ㅂㅅ", true},
+		{"스타벅스", "벅스ㅈ", false},
+		{"ＣＵ 편의점", "u편", true},
+		{"카페", "라떼", false},
+	}
+	for _, tt := range tests {
+		if got := Contains(tt.text, tt.query); got != tt.want {
+			t.Errorf("Contains(%q, %q) = %v, want %v", tt.text, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestBigrams(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"스타벅스", "스타 타벅 벅스"},
+		{"스타 벅스", "스타 타벅 벅스"},
+		{"C.U 편의점", "cu u편 편의 의점"},
+		{"카", "카"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Bigrams(tt.in); got != tt.want {
+			t.Errorf("Bigrams(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -4,7 +4,12 @@ import type {
   Favorite, 
   SearchHistory, 
   PopularKeyword, 
-  CacheEntry
+  CacheEntry,
+  PlaceDetailInfo,
+  User,
+  UserSession,
+  MergeSummary,
+  DeletionJob
 } from '../types';
 
 // Cache API
@@ -33,10 +38,64 @@ export const historyAPI = {
   getPopular: (limit = 10) =>
     api.get<{ keywords: PopularKeyword[]; count: number }>(`/history/popular?limit=${limit}`),
   
+  search: (query: string, limit = 20) =>
+    api.get<{ history: SearchHistory[]; count: number }>(
+      `/history?q=${encodeURIComponent(query)}&limit=${limit}`
+    ),
+
+  remove: (id: number) =>
+    api.delete<{ message: string }>(`/history/${id}`),
+
+  removeKeyword: (keyword: string) =>
+    api.delete<{ message: string }>(`/history?keyword=${encodeURIComponent(keyword)}`),
+
   clear: () =>
     api.delete<{ message: string }>('/history'),
 };
 
+// Places API (local catalog, no Kakao call)
+export const placesAPI = {
+  get: (placeId: string) =>
+    api.get<PlaceDetailInfo>(`/places/${encodeURIComponent(placeId)}`),
+
+  search: (query: string, limit = 20) =>
+    api.get<{ places: Place[]; count: number; query: string }>(
+      `/places/search?q=${encodeURIComponent(query)}&limit=${limit}`
+    ),
+};
+
+// Auth API (Kakao login and sessions)
+export const authAPI = {
+  loginURL: (redirect = window.location.pathname) =>
+    `/api/auth/kakao/login?redirect=${encodeURIComponent(redirect)}`,
+
+  me: () =>
+    api.get<{ authenticated: boolean; login_enabled: boolean; user: User | null }>('/auth/me'),
+
+  sessions: () =>
+    api.get<{ sessions: UserSession[]; count: number }>('/auth/sessions'),
+
+  merge: () =>
+    api.post<{ summary: MergeSummary }>('/auth/merge', {}),
+
+  logout: () =>
+    api.post<{ message: string }>('/auth/logout', {}),
+
+  logoutAll: () =>
+    api.post<{ revoked: number }>('/auth/logout-all', {}),
+};
+
+// Personal data API (export and deletion)
+export const meAPI = {
+  exportURL: '/api/me/export',
+
+  requestDeletion: () =>
+    api.delete<DeletionJob>('/me'),
+
+  getDeletion: (jobId: string) =>
+    api.get<DeletionJob>(`/me/deletions/${encodeURIComponent(jobId)}`),
+};
+
 // Directions API
 export const directionsAPI = {
   getDirections: (origin: string, destination: string) =>
@@ -47,5 +106,8 @@ export default {
   cache: cacheAPI,
   favorites: favoritesAPI,
   history: historyAPI,
+  places: placesAPI,
+  auth: authAPI,
+  me: meAPI,
   directions: directionsAPI,
 };
@@ -89,7 +89,7 @@ export const HistoryPanel: React.FC<HistoryPanelProps> = ({
                   <div className="history-item-info">
                     <span className="history-item-keyword">{item.keyword}</span>
                     <span className="history-item-meta">
-                      {item.result_count}건 · {formatDate(item.searched_at)}
+                      {item.result_count}건 · {formatDate(item.last_searched_at)}
                     </span>
                   </div>
                 </button>
@@ -14,6 +14,24 @@ export interface Place {
   distance: string;
 }
 
+// Search keyword a place appeared for
+export interface PlaceAppearance {
+  keyword: string;
+  count: number;
+  first_seen: string;
+  last_seen: string;
+}
+
+// Place from the local catalog
+export interface PlaceDetailInfo extends Place {
+  source: 'search' | 'favorite';
+  first_seen: string;
+  last_seen: string;
+  favorite_count: number;
+  is_favorite: boolean;
+  appearances: PlaceAppearance[];
+}
+
 // Favorite place
 export interface Favorite {
   id: number;
@@ -34,8 +52,10 @@ export interface SearchHistory {
   id: number;
   user_id: string;
   keyword: string;
+  count: number;
   result_count: number;
-  searched_at: string;
+  first_searched_at: string;
+  last_searched_at: string;
 }
 
 // Popular keyword
@@ -52,6 +72,49 @@ export interface CacheEntry {
   cached_at?: string;
 }
 
+// Logged-in account
+export interface User {
+  id: number;
+  provider: 'kakao';
+  nickname: string;
+  profile_image_url?: string;
+  created_at: string;
+  last_login_at: string;
+}
+
+// Login session of the current account
+export interface UserSession {
+  id: number;
+  user_agent: string;
+  created_at: string;
+  last_seen_at: string;
+  expires_at: string;
+  current: boolean;
+}
+
+// What an anonymous browser's data contributed to the account on login
+export interface MergeSummary {
+  favorites: number;
+  favorites_merged: number;
+  history: number;
+  history_merged: number;
+  search_events: number;
+  visits: number;
+  shared_lists: number;
+}
+
+// Personal data deletion request
+export interface DeletionJob {
+  id: string;
+  status: 'pending' | 'running' | 'done' | 'failed';
+  deleted_rows: number;
+  anonymized_rows: number;
+  error?: string;
+  requested_at: string;
+  started_at?: string;
+  finished_at?: string;
+}
+
 // Coordinates
 export interface Coordinates {
   lat: number;